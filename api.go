@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- API TOKENS --- */
+
+// apiTokens are the owners of the API tokens, indexed by the hash of the token
+var apiTokens = map[string]int64{}
+
+// IssueToken creates a new API token for the user, replacing the previous one
+func IssueToken(userID int64) (string, error) {
+	var raw = make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	RevokeToken(userID)
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	apiTokens[hashToken(token)] = userID
+	return token, nil
+}
+
+// RevokeToken deletes the API token of the user, if any
+func RevokeToken(userID int64) (revoked bool) {
+	for hash, ownerID := range apiTokens {
+		if ownerID == userID {
+			delete(apiTokens, hash)
+			revoked = true
+		}
+	}
+	return
+}
+
+// TokenOwner returns the ID of the user owning the given API token
+func TokenOwner(token string) (userID int64, ok bool) {
+	if token == "" {
+		return 0, false
+	}
+	userID, ok = apiTokens[hashToken(token)]
+	return
+}
+
+// hashToken is used to avoid saving the tokens in clear
+func hashToken(token string) string {
+	var sum = sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/* --- API SERVER --- */
+
+// Format of the dates used by the API
+const API_DATE_FORMAT = "2006-01-02T15:04"
+
+const (
+	API_MAX_BODY        = 1 << 16          // max size of the body of the API requests
+	HTTP_HEADER_TIMEOUT = time.Second * 10 // max time to read the headers of a request
+	HTTP_READ_TIMEOUT   = time.Second * 30 // max time to read a whole request
+	HTTP_WRITE_TIMEOUT  = time.Second * 30 // max time to write a response
+	HTTP_IDLE_TIMEOUT   = time.Minute * 2  // max time a connection is kept waiting for the next request
+)
+
+//go:embed api/openapi.json
+var openAPI []byte
+
+// StartServer starts the HTTP server on the configured address, when there is one
+func StartServer() {
+	if config.HTTPAddress == "" {
+		return
+	}
+
+	var server = &http.Server{
+		Addr:              config.HTTPAddress,
+		Handler:           NewRouter(),
+		ReadHeaderTimeout: HTTP_HEADER_TIMEOUT,
+		ReadTimeout:       HTTP_READ_TIMEOUT,
+		WriteTimeout:      HTTP_WRITE_TIMEOUT,
+		IdleTimeout:       HTTP_IDLE_TIMEOUT,
+	}
+	go func() {
+		log.Fatal("HTTP server error: ", server.ListenAndServe())
+	}()
+}
+
+// NewRouter returns the handler of all the HTTP endpoints
+func NewRouter() http.Handler {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	mux.HandleFunc("/api/calendar", authenticated(serveCalendar))
+	mux.HandleFunc("/api/calendar/", authenticated(serveEvents))
+	mux.HandleFunc("/api/calendar/history", authenticated(serveHistory))
+	mux.HandleFunc("/feed/", serveFeed)
+	mux.HandleFunc("/caldav", serveCalDAV)
+	mux.HandleFunc("/caldav/", serveCalDAV)
+	mux.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+	return mux
+}
+
+// apiError is the body of the responses in case of error
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiCalendar is the representation of a calendar in the API
+type apiCalendar struct {
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Notification bool       `json:"notification"`
+	Capacity     int        `json:"capacity"`
+	Duration     string     `json:"duration"`
+	Invitation   string     `json:"invitation"`
+	ShareLink    string     `json:"share_link"`
+	Events       []apiEvent `json:"events"`
+}
+
+// apiEvent is the representation of a date of a calendar in the API
+type apiEvent struct {
+	Date      string  `json:"date"`
+	Attendees []int64 `json:"attendees"`
+	Attended  []int64 `json:"attended"`   // who checked in, also without joining
+	Days      int     `json:"days"`       // covered by an all-day event, 0 for the events at a given time
+	FreeSeats *int    `json:"free_seats"` // nil when unlimited
+}
+
+// apiCalendarEdit is the body used to create or edit a calendar, missing fields are left unchanged
+type apiCalendarEdit struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	Notification *bool   `json:"notification"`
+	Capacity     *int    `json:"capacity"`
+	Duration     *string `json:"duration"`
+}
+
+// apiAttendee is the body used to make a user join an event
+type apiAttendee struct {
+	UserID    int64  `json:"user_id"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username"`
+}
+
+// apiHandler is an http.HandlerFunc for a request made by an organizer
+type apiHandler func(w http.ResponseWriter, r *http.Request, userID int64)
+
+// authenticated checks the API token and holds the data lock while handling the request
+func authenticated(handler apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		// the body is read before locking the data to not wait for slow clients
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, API_MAX_BODY))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
+		userID, ok := TokenOwner(token)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid API token")
+			return
+		}
+		handler(w, r, userID)
+	}
+}
+
+// serveCalendar handles /api/calendar
+func serveCalendar(w http.ResponseWriter, r *http.Request, userID int64) {
+	var calendar = CalendarOf(userID)
+
+	switch r.Method {
+	case http.MethodGet:
+		if calendar == nil {
+			writeError(w, http.StatusNotFound, "not_found", "no calendar found")
+			return
+		}
+		writeJSON(w, http.StatusOK, toAPICalendar(*calendar))
+
+	case http.MethodPost:
+		if calendar != nil {
+			writeError(w, http.StatusConflict, "conflict", "calendar already exists")
+			return
+		}
+		var body apiCalendarEdit
+		if !readJSON(w, r, &body) {
+			return
+		}
+		if body.Name == nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", "missing name")
+			return
+		}
+
+		changes, err := changesFromAPI(body)
+		if err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+
+		calendar = AddToCalendar(echotron.User{ID: userID, FirstName: *body.Name})
+		editFromAPI(calendar, changes, userID)
+		writeJSON(w, http.StatusCreated, toAPICalendar(*calendar))
+
+	case http.MethodPatch:
+		if calendar == nil {
+			writeError(w, http.StatusNotFound, "not_found", "no calendar found")
+			return
+		}
+		var body apiCalendarEdit
+		if !readJSON(w, r, &body) {
+			return
+		}
+		changes, err := changesFromAPI(body)
+		if err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		editFromAPI(calendar, changes, userID)
+		writeJSON(w, http.StatusOK, toAPICalendar(*calendar))
+
+	case http.MethodDelete:
+		if DeleteCalendar(userID) == nil {
+			writeError(w, http.StatusNotFound, "not_found", "no calendar found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+// serveHistory handles /api/calendar/history
+func serveHistory(w http.ResponseWriter, r *http.Request, userID int64) {
+	var calendar = CalendarOf(userID)
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if calendar == nil {
+		writeError(w, http.StatusNotFound, "not_found", "no calendar found")
+		return
+	}
+
+	var events = []apiEvent{}
+	for _, date := range calendar.SortedArchive() {
+		events = append(events, toAPIEvent(*calendar, date))
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// serveEvents handles /api/calendar/events, /api/calendar/events/{date},
+// /api/calendar/events/{date}/attendees and /api/calendar/events/{date}/attendees/{user_id}
+func serveEvents(w http.ResponseWriter, r *http.Request, userID int64) {
+	var (
+		path     = strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/calendar/"), "/"), "/")
+		calendar = CalendarOf(userID)
+		date     Date
+	)
+	if path[0] != "events" || len(path) > 4 || (len(path) > 2 && path[2] != "attendees") {
+		writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+		return
+	}
+
+	if calendar == nil {
+		writeError(w, http.StatusNotFound, "not_found", "no calendar found")
+		return
+	}
+	if len(path) > 1 {
+		var err error
+		if date, err = ParseAPIDate(path[1]); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_date", "dates must be in the format "+API_DATE_FORMAT)
+			return
+		}
+		if calendar.dates[date.Formatted()] == nil {
+			writeError(w, http.StatusNotFound, "not_found", "no event in the given date")
+			return
+		}
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, toAPICalendar(*calendar).Events)
+
+	case len(path) == 1 && r.Method == http.MethodPost:
+		var body struct {
+			Date string `json:"date"`
+			Days int    `json:"days"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		date, err := ParseAPIDate(body.Date)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_date", "dates must be in the format "+API_DATE_FORMAT)
+			return
+		}
+		if body.Days < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_days", "days can not be negative")
+			return
+		}
+		if body.Days > 0 {
+			date = date.DayStart()
+		}
+		if !date.IsAfter(Now()) && (body.Days == 0 || date.IsBefore(Now().DayStart())) {
+			writeError(w, http.StatusBadRequest, "invalid_date", "the date has already passed")
+			return
+		}
+		if calendar.dates[date.Formatted()] != nil {
+			writeError(w, http.StatusConflict, "conflict", "there is already an event in the given date")
+			return
+		}
+		if body.Days > 0 {
+			AddSpanToCalendar(echotron.User{ID: userID}, date, date.Skip(0, 0, body.Days-1))
+		} else {
+			AddToCalendar(echotron.User{ID: userID}, date)
+		}
+		writeJSON(w, http.StatusCreated, toAPIEvent(*calendar, date.Formatted()))
+
+	case len(path) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, toAPIEvent(*calendar, date.Formatted()))
+
+	case len(path) == 2 && r.Method == http.MethodDelete:
+		RemoveFromCalendar(calendar, date.Formatted())
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(path) == 3 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, toAPIEvent(*calendar, date.Formatted()).Attendees)
+
+	case len(path) == 3 && r.Method == http.MethodPost:
+		var body apiAttendee
+		if !readJSON(w, r, &body) {
+			return
+		}
+		if body.UserID == 0 {
+			writeError(w, http.StatusBadRequest, "invalid_body", "missing user_id")
+			return
+		}
+		user := echotron.User{ID: body.UserID, FirstName: body.FirstName, Username: body.Username}
+		if _, err := JoinEvent(user, calendar.invitation, string(date.Formatted()), true, userID); err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toAPIEvent(*calendar, date.Formatted()))
+
+	case len(path) == 4 && r.Method == http.MethodDelete:
+		attendeeID, err := strconv.ParseInt(path[3], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_user", "invalid user ID")
+			return
+		}
+		if _, err = LeaveEvent(attendeeID, calendar.invitation, string(date.Formatted()), userID); err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+// changesFromAPI grabs the field-value pairs to edit from the body, failing if any of them is invalid
+func changesFromAPI(body apiCalendarEdit) (changes [][2]string, err error) {
+	if body.Name != nil {
+		changes = append(changes, [2]string{"name", *body.Name})
+	}
+	if body.Description != nil {
+		changes = append(changes, [2]string{"description", *body.Description})
+	}
+	if body.Notification != nil {
+		changes = append(changes, [2]string{"notification", toggler(*body.Notification).String()})
+	}
+	if body.Capacity != nil {
+		changes = append(changes, [2]string{"capacity", strconv.Itoa(*body.Capacity)})
+	}
+	if body.Duration != nil {
+		changes = append(changes, [2]string{"duration", *body.Duration})
+	}
+
+	for _, change := range changes {
+		if err = ValidateEdit(change[0], change[1]); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// editFromAPI applies the given changes to the calendar on behalf of the user
+func editFromAPI(calendar *Calendar, changes [][2]string, userID int64) {
+	for _, change := range changes {
+		EditCalendar(calendar, change[0], change[1], userID)
+	}
+}
+
+// ParseAPIDate parses a date in the format used by the API
+func ParseAPIDate(source string) (Date, error) {
+	var date, err = time.ParseInLocation(API_DATE_FORMAT, source, config.location)
+	if err != nil {
+		return Date{}, err
+	}
+	return Parse(date), nil
+}
+
+func toAPICalendar(c Calendar) apiCalendar {
+	var events = []apiEvent{}
+	for _, date := range c.SortedDates() {
+		events = append(events, toAPIEvent(c, date))
+	}
+
+	return apiCalendar{
+		Name:         c.name,
+		Description:  c.description,
+		Notification: bool(c.notification),
+		Capacity:     c.capacity,
+		Duration:     Duration(c.Duration()).String(),
+		Invitation:   c.invitation,
+		ShareLink:    GetShareLink(telegram.Username(), c),
+		Events:       events,
+	}
+}
+
+func toAPIEvent(c Calendar, date FormattedDate) apiEvent {
+	var event = apiEvent{
+		Date:      string(date),
+		Attendees: []int64{},
+		Attended:  []int64{},
+	}
+	if found := c.event(date); found != nil {
+		event.Attendees = append(event.Attendees, found.attendee...)
+		event.Attended = append(event.Attended, found.attended...)
+		event.Days = found.days
+	}
+	if parsed, err := date.Parse(); err == nil {
+		event.Date = parsed.Format(API_DATE_FORMAT)
+	}
+	sort.Slice(event.Attendees, func(i, j int) bool { return event.Attendees[i] < event.Attendees[j] })
+	sort.Slice(event.Attended, func(i, j int) bool { return event.Attended[i] < event.Attended[j] })
+
+	if c.capacity > 0 {
+		free := c.capacity - len(event.Attendees)
+		if free < 0 {
+			free = 0
+		}
+		event.FreeSeats = &free
+	}
+	return event
+}
+
+// readJSON decodes the body of the request, already read by authenticated, writing
+// the error response when invalid
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	var decoder = json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Code: code, Message: message})
+}
+
+// writeCalendarError writes the response for an error returned by the calendar's logic
+func writeCalendarError(w http.ResponseWriter, err error) {
+	var calendarErr CalendarError
+	if !errors.As(err, &calendarErr) {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+
+	var status = http.StatusBadRequest
+	switch calendarErr {
+	case ALREADY_JOINED, EVENT_FULL:
+		status = http.StatusConflict
+	case INVALID_CALENDAR, INVALID_EVENT, NOT_JOINED:
+		status = http.StatusNotFound
+	}
+	writeError(w, status, strings.TrimPrefix(string(calendarErr), "error."), calendarErr.Error())
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- END TO END --- */
+
+var (
+	organizer = echotron.User{ID: 1, FirstName: "Ada"}
+	invitee   = echotron.User{ID: 2, FirstName: "Bob"}
+)
+
+// newTestBot resets the data of the bot and drives it with a FakeTelegram and
+// a FakeClock set at noon of the 10th of January 2030
+func newTestBot(t *testing.T) (*FakeTelegram, *FakeClock) {
+	t.Helper()
+
+	config = DefaultConfiguration()
+	config.location = time.UTC
+	config.RateLimit.Requests = 0
+
+	organizers = map[int64]*Calendar{}
+	preferences = map[int64]*Preferences{}
+	timers = map[*Calendar]map[FormattedDate][]Timer{}
+	selections = map[int64]map[FormattedDate]bool{}
+	conversations = map[int64]*Conversation{}
+	expirations = map[int64]Timer{}
+	inlineCards = map[string]inlineCard{}
+	webhooks = map[int64][]*Webhook{}
+	checkIns = map[string]*checkIn{}
+
+	return NewFakeTelegram("calendaggerbill_bot"), NewFakeClock(time.Date(2030, time.January, 10, 12, 0, 0, 0, time.UTC))
+}
+
+// send dispatches the update as if it was sent by the user, failing if there is none
+func send(t *testing.T, user echotron.User, update *message.Update) {
+	t.Helper()
+	if update == nil {
+		t.Fatal("there is nothing to send, the button might be missing")
+	}
+	Dispatch(user.ID, update)
+}
+
+// publish creates the calendar of the organizer with an event in the given day
+// using the month grid of /publish
+func publish(t *testing.T, fake *FakeTelegram, day Date) *Calendar {
+	t.Helper()
+
+	send(t, organizer, fake.Write(organizer, "/publish"))
+	var grid = fake.Last(organizer.ID)
+	send(t, organizer, fake.PressData(organizer, grid, "/publish "+string(day.Formatted())+" add"))
+
+	calendar := CalendarOf(organizer.ID)
+	if calendar == nil || calendar.dates[day.Formatted()] == nil {
+		t.Fatalf("the event of the %s has not been published", day.Formatted())
+	}
+	return calendar
+}
+
+// assertLast checks that the last message visible in the chat contains all the texts
+func assertLast(t *testing.T, fake *FakeTelegram, chatID int64, texts ...string) *FakeMessage {
+	t.Helper()
+
+	var last = fake.Last(chatID)
+	if last == nil {
+		t.Fatalf("no message in the chat %d", chatID)
+	}
+	for _, text := range texts {
+		if !strings.Contains(last.Text, text) {
+			t.Fatalf("the last message does not contain %q:\n%s", text, last.Text)
+		}
+	}
+	return last
+}
+
+// assertCallbacks checks that all the buttons of the message fit in the callback data limit
+func assertCallbacks(t *testing.T, msg *FakeMessage) {
+	t.Helper()
+	for _, row := range msg.Keyboard {
+		for _, button := range row {
+			if len(button.CallbackData) > 64 {
+				t.Fatalf("callback data of %q is %d bytes long: %s", button.Text, len(button.CallbackData), button.CallbackData)
+			}
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	fake, _ := newTestBot(t)
+
+	send(t, organizer, fake.Write(organizer, "/start"))
+	welcome := assertLast(t, fake, organizer.ID, "Welcome")
+	if fake.PressData(organizer, welcome, "/publish "+string(Today())) == nil {
+		t.Fatal("missing the button to create the calendar")
+	}
+
+	publish(t, fake, Now().Skip(0, 0, 5))
+	send(t, organizer, fake.Write(organizer, "/start"))
+	menu := assertLast(t, fake, organizer.ID, "Ada calendar")
+	for _, trigger := range []string{"/edit", "/link", "/changes", "/settings"} {
+		if fake.PressData(organizer, menu, trigger) == nil {
+			t.Fatalf("missing the button of %s in the organizer's menu", trigger)
+		}
+	}
+	assertCallbacks(t, menu)
+}
+
+func TestPublish(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var day = Now().Skip(0, 0, 5)
+
+	send(t, organizer, fake.Write(organizer, "/publish"))
+	grid := fake.Last(organizer.ID)
+	assertCallbacks(t, grid)
+	send(t, organizer, fake.PressData(organizer, grid, "/publish "+string(day.Formatted())+" add"))
+
+	assertLast(t, fake, organizer.ID, GetShareLink(fake.Username(), *CalendarOf(organizer.ID)))
+	if len(fake.Answers) == 0 || !strings.Contains(fake.Answers[len(fake.Answers)-1], "added") {
+		t.Fatalf("the organizer has not been told that the date is added: %q", fake.Answers)
+	}
+
+	// adding a second day to the existing calendar keeps the grid
+	var other = day.Skip(0, 0, 1)
+	send(t, organizer, fake.Write(organizer, "/publish"))
+	grid = fake.Last(organizer.ID)
+	send(t, organizer, fake.PressData(organizer, grid, "/publish "+string(other.Formatted())+" add"))
+	if calendar := CalendarOf(organizer.ID); len(calendar.dates) != 2 {
+		t.Fatalf("expected 2 dates, got %d", len(calendar.dates))
+	}
+}
+
+func TestJoin(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 5)
+		calendar = publish(t, fake, day)
+	)
+
+	send(t, invitee, fake.Write(invitee, "/start "+calendar.invitation))
+	list := assertLast(t, fake, invitee.ID, calendar.name)
+	send(t, invitee, fake.PressData(invitee, list, "/join "+calendar.invitation+" "+string(day.Formatted())))
+
+	if !calendar.dates[day.Formatted()].hasJoined(invitee.ID) {
+		t.Fatal("the invitee has not joined the event")
+	}
+	assertLast(t, fake, organizer.ID, "Bob")
+
+	// joining again is refused
+	send(t, invitee, fake.PressData(invitee, list, "/join "+calendar.invitation+" "+string(day.Formatted())))
+	if calendar.CountAttendee(day.Formatted()) != 1 {
+		t.Fatalf("expected 1 attendee, got %d", calendar.CountAttendee(day.Formatted()))
+	}
+}
+
+func TestJoinDeletedCalendar(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 5)
+		calendar = publish(t, fake, day)
+	)
+
+	send(t, invitee, fake.Write(invitee, "/start "+calendar.invitation))
+	list := fake.Last(invitee.ID)
+	DeleteCalendar(organizer.ID)
+
+	send(t, invitee, fake.PressData(invitee, list, "/join "+calendar.invitation+" "+string(day.Formatted())))
+	assertLast(t, fake, invitee.ID, DEFAULT_LANGUAGE.T(string(INVALID_CALENDAR)))
+}
+
+func TestEdit(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var calendar = publish(t, fake, Now().Skip(0, 0, 5))
+
+	send(t, organizer, fake.Write(organizer, "/edit"))
+	send(t, organizer, fake.PressData(organizer, fake.Last(organizer.ID), "/conversation answer description"))
+	send(t, organizer, fake.Write(organizer, "Weekly <b>chess</b> club"))
+
+	confirm := assertLast(t, fake, organizer.ID, "Weekly &lt;b&gt;chess&lt;/b&gt; club")
+	if calendar.description == "Weekly <b>chess</b> club" {
+		t.Fatal("the description changed before the confirmation")
+	}
+	send(t, organizer, fake.PressData(organizer, confirm, "/conversation confirm"))
+
+	if calendar.description != "Weekly <b>chess</b> club" {
+		t.Fatalf("the description has not been changed: %q", calendar.description)
+	}
+	if ConversationOf(organizer.ID) != nil {
+		t.Fatal("the conversation is still in progress after the confirmation")
+	}
+}
+
+func TestEditCancel(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var calendar = publish(t, fake, Now().Skip(0, 0, 5))
+
+	send(t, organizer, fake.Write(organizer, "/edit name Chess club"))
+	send(t, organizer, fake.PressData(organizer, fake.Last(organizer.ID), "/conversation cancel"))
+	if calendar.name != "Ada calendar" {
+		t.Fatalf("the name changed even if the edit was cancelled: %q", calendar.name)
+	}
+}
+
+func TestSet(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 5)
+		calendar = publish(t, fake, day)
+		name     = strings.Repeat("A very long name ", 10)
+	)
+	send(t, invitee, fake.Write(invitee, "/start "+calendar.invitation))
+	send(t, invitee, fake.PressData(invitee, fake.Last(invitee.ID), "/join "+calendar.invitation+" "+string(day.Formatted())))
+
+	// the value is kept by the conversation, not in the confirmation button
+	send(t, organizer, fake.Write(organizer, "/edit name "+name))
+	confirm := fake.Last(organizer.ID)
+	assertCallbacks(t, confirm)
+	send(t, organizer, fake.PressData(organizer, confirm, "/conversation confirm"))
+
+	if calendar.name != strings.TrimSpace(name) {
+		t.Fatalf("the name has not been changed: %q", calendar.name)
+	}
+	done := assertLast(t, fake, organizer.ID, "successfully changed")
+	assertCallbacks(t, done)
+	assertLast(t, fake, invitee.ID, strings.TrimSpace(name))
+
+	// the change is logged and can be turned back
+	if change := calendar.lastChange(); change == nil || change.Kind != CHANGE_EDITED || change.Actor != organizer.ID {
+		t.Fatalf("the edit has not been logged as made by the organizer: %+v", change)
+	}
+	send(t, organizer, fake.Press(organizer, done, "↩️"))
+	send(t, organizer, fake.Press(organizer, fake.Last(organizer.ID), "Confirm"))
+	if calendar.name != "Ada calendar" {
+		t.Fatalf("the name has not been turned back: %q", calendar.name)
+	}
+}
+
+func TestToggleNotification(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var calendar = publish(t, fake, Now().Skip(0, 0, 5))
+	var changes = len(calendar.changes)
+
+	send(t, organizer, fake.Write(organizer, "/edit notification toggle"))
+	send(t, organizer, fake.PressData(organizer, fake.Last(organizer.ID), "/conversation cancel"))
+	if !calendar.notification || len(calendar.changes) != changes {
+		t.Fatal("the notification changed even if the edit was cancelled")
+	}
+
+	send(t, organizer, fake.Write(organizer, "/edit notification toggle"))
+	send(t, organizer, fake.PressData(organizer, fake.Last(organizer.ID), "/conversation confirm"))
+	if calendar.notification {
+		t.Fatal("the notification has not been turned off")
+	}
+	if len(calendar.changes) != changes+1 {
+		t.Fatalf("expected 1 change logged, got %d", len(calendar.changes)-changes)
+	}
+	if change := calendar.lastChange(); change.Kind != CHANGE_EDITED || change.Field != "notification" || change.Previous != "on" || change.Value != "off" {
+		t.Fatalf("the toggle has not been logged: %+v", *change)
+	}
+}
+
+func TestLink(t *testing.T) {
+	fake, _ := newTestBot(t)
+
+	send(t, organizer, fake.Write(organizer, "/link"))
+	assertLast(t, fake, organizer.ID, "You don't have a calendar")
+
+	var (
+		day      = Now().Skip(0, 0, 5)
+		calendar = publish(t, fake, day)
+		link     = GetShareLink(fake.Username(), *calendar)
+	)
+	send(t, organizer, fake.Write(organizer, "/link"))
+	assertLast(t, fake, organizer.ID, link)
+
+	// the shared link opens the calendar to the invitees
+	var payload = link[strings.Index(link, "?start=")+len("?start="):]
+	send(t, invitee, fake.Write(invitee, "/start "+payload))
+	assertLast(t, fake, invitee.ID, calendar.name)
+}
+
+func TestEventLink(t *testing.T) {
+	var startPayload = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	for _, test := range []struct {
+		invitation string
+		date       FormattedDate
+	}{
+		{"1", "10/01/2030T12:00"},
+		{"123456789", "29/02/2032T00:00"},
+		{strconv.FormatInt(math.MaxInt64, 10), "31/12/9999T23:59"}, // longest payload
+		{strconv.FormatInt(math.MaxInt64, 10), "01/01/1970T00:00"},
+	} {
+		payload, ok := EncodeEventLink(test.invitation, test.date)
+		if !ok || !startPayload.MatchString(payload) {
+			t.Fatalf("invalid start payload %q for %s %s", payload, test.invitation, test.date)
+		}
+		if invitation, date, ok := DecodeEventLink(payload); !ok || invitation != test.invitation || date != test.date {
+			t.Fatalf("%q decoded as %s %s, expected %s %s", payload, invitation, date, test.invitation, test.date)
+		}
+	}
+
+	for _, test := range []struct {
+		invitation string
+		date       FormattedDate
+	}{
+		{"", "10/01/2030T12:00"},
+		{"calendar", "10/01/2030T12:00"},
+		{"0", "10/01/2030T12:00"},
+		{"-42", "10/01/2030T12:00"},
+		{"9223372036854775808", "10/01/2030T12:00"}, // overflows int64
+		{"1", ""},
+		{"1", "10/01/2030"},
+		{"1", "32/01/2030T12:00"},
+	} {
+		if payload, ok := EncodeEventLink(test.invitation, test.date); ok || payload != "" {
+			t.Errorf("%s %s encoded as %q", test.invitation, test.date, payload)
+		}
+	}
+	for _, payload := range []string{"", "e", "e1", "x1-1", "e-1-1", "e0-1", "e1--1", "e1-", "e1-?"} {
+		if _, _, ok := DecodeEventLink(payload); ok {
+			t.Errorf("%q decoded as an event link", payload)
+		}
+	}
+
+	// a date that can't be encoded links to the whole calendar
+	var calendar = Calendar{invitation: "1"}
+	if link := GetEventLink("calendaggerbill_bot", calendar, "invalid"); link != GetShareLink("calendaggerbill_bot", calendar) {
+		t.Fatalf("unexpected link %q", link)
+	}
+}
+
+func TestInlineCardEscaped(t *testing.T) {
+	fake, _ := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 5)
+		calendar = publish(t, fake, day)
+	)
+	EditCalendar(calendar, "name", UNSAFE_NAME, organizer.ID)
+
+	send(t, organizer, fake.Query(organizer, ""))
+	update, card := fake.Choose(organizer, NewCard(CALENDAR_CARD, calendar.invitation, "").ID())
+	if update == nil {
+		t.Fatal("the calendar is not among the inline results")
+	}
+	if !strings.Contains(card.Text, html.EscapeString(UNSAFE_NAME)) {
+		t.Fatalf("the name of the calendar is not escaped in the card:\n%s", card.Text)
+	}
+	send(t, organizer, update)
+
+	update, card = fake.Choose(organizer, NewCard(EVENT_CARD, calendar.invitation, day.Formatted()).ID())
+	if update == nil || !strings.Contains(card.Text, html.EscapeString(UNSAFE_NAME)) {
+		t.Fatal("the name of the calendar is not escaped in the card of the event")
+	}
+}
+
+// UNSAFE_NAME is a valid name of a calendar that breaks the HTML when not escaped
+const UNSAFE_NAME = "R&D <meetup>"
+
+func TestNamesEscaped(t *testing.T) {
+	fake, clock := newTestBot(t)
+	var (
+		day      = Now()
+		date     = day.Formatted()
+		calendar = publish(t, fake, day)
+		lang     = DEFAULT_LANGUAGE
+	)
+	EditCalendar(calendar, "name", UNSAFE_NAME, organizer.ID)
+	join(t, fake, calendar, date)
+
+	open, err := OpenCheckIn(calendar, date)
+	if err != nil {
+		t.Fatal("the check-in can't be opened: ", err)
+	}
+	checkIn, _ := buildCheckInMessage(lang, *calendar, date, *open)
+	stats, _ := buildStatsMessage(lang, *calendar, ComputeStats(*calendar, time.Monday))
+	conflict, _ := buildConflictMessage(lang, *calendar, date, []Conflict{{calendar, date}})
+	templates, _ := buildTemplatesMessage(lang, *calendar)
+	changes, _ := buildChangesMessage(lang, *calendar, organizer.ID, 0)
+	assertEscaped(t, map[string]string{
+		"changes":       changes,
+		"templates":     templates,
+		"conflict":      conflict,
+		"check-in":      checkIn,
+		"check-in done": buildCheckInDone(lang, *calendar, date).Text,
+		"statistics":    stats,
+	})
+
+	// the attendees warned when a change is undone
+	if change := calendar.lastChange(); change == nil || change.Kind != CHANGE_JOINED {
+		t.Fatal("the join has not been logged")
+	} else if _, err := UndoChange(calendar, change.ID, organizer.ID); err != nil {
+		t.Fatal("the join can't be undone: ", err)
+	}
+	assertEscaped(t, map[string]string{"undo warning": fake.Last(invitee.ID).Text})
+
+	// the screens of the events that occurred
+	clock.Advance(time.Minute)
+	if calendar.archive[date] == nil {
+		t.Fatal("the event has not been archived")
+	}
+	summary, _ := buildFeedbackSummary(lang, *calendar)
+	history, _ := buildHistoryMessage(lang, *calendar, 0)
+	assertEscaped(t, map[string]string{
+		"history":          history,
+		"roster":           buildRoster(lang, *calendar, date),
+		"feedback request": buildFeedbackRequest(lang, *calendar, date).Text,
+		"feedback summary": summary,
+		"feedback detail":  buildFeedbackDetail(lang, *calendar, date),
+	})
+}
+
+// assertEscaped checks that the name of the calendar is escaped in the text of each screen
+func assertEscaped(t *testing.T, screens map[string]string) {
+	t.Helper()
+	for screen, text := range screens {
+		if strings.Contains(text, UNSAFE_NAME) || !strings.Contains(text, html.EscapeString(UNSAFE_NAME)) {
+			t.Errorf("the name of the calendar is not escaped in the %s screen:\n%s", screen, text)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- BULK ADDITION --- */
+
+// Payloads of /publish used to add several days at once at the same time
+const (
+	SELECT_VIEW  = "select" // month grid where the days are selected
+	SELECT_DAY   = "toggle" // selects or deselects a day
+	SELECT_CLEAR = "clear"  // deselects all the days
+	HOUR_VIEW    = "hour"   // choice of the hour of the dates
+	MINUTE_VIEW  = "minute" // choice of the minutes of the dates
+	BULK_REVIEW  = "review" // confirmation of the dates that will be added
+	BULK_ADD     = "bulk"   // adds the selected days at the time of the payload
+)
+
+// Minutes proposed for the time of the selected days
+var BULK_MINUTES = []int{0, 15, 30, 45}
+
+// selections are the days selected by each organizer, at midnight, not saved on the storage
+var selections = map[int64]map[FormattedDate]bool{}
+
+// ToggleDay selects a day for the user or deselects it, if it was already
+func ToggleDay(userID int64, day Date) (selected bool) {
+	var key = day.DayStart().Formatted()
+	if selections[userID][key] {
+		delete(selections[userID], key)
+		return false
+	}
+	if selections[userID] == nil {
+		selections[userID] = map[FormattedDate]bool{}
+	}
+	selections[userID][key] = true
+	return true
+}
+
+// SelectedDays returns the days selected by the user in chronological order
+func SelectedDays(userID int64) (days []Date) {
+	for key := range selections[userID] {
+		if day, err := key.Parse(); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].IsBefore(days[j]) })
+	return
+}
+
+func clearSelection(userID int64) {
+	delete(selections, userID)
+}
+
+// atTime returns the selected days at the same time of the given date
+func atTime(days []Date, at Date) (dates []Date) {
+	for _, day := range days {
+		dates = append(dates, Parse(time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location())))
+	}
+	return
+}
+
+// AddSelectedDays adds to the calendar of the user the selected days at the time of
+// the given date, returning the dates added, the ones that already were in the
+// calendar and the ones skipped because already passed, then clears the selection
+func AddSelectedDays(user echotron.User, at Date) (calendar *Calendar, added, existing, passed []FormattedDate) {
+	var (
+		now      = Now()
+		upcoming []Date
+		isAdded  = map[FormattedDate]bool{}
+	)
+	for _, date := range atTime(SelectedDays(user.ID), at) {
+		if date.IsBefore(now) {
+			passed = append(passed, date.Formatted())
+		} else {
+			upcoming = append(upcoming, date)
+		}
+	}
+
+	calendar, added = AddDatesToCalendar(user, upcoming...)
+	for _, date := range added {
+		isAdded[date] = true
+	}
+	for _, date := range upcoming {
+		if !isAdded[date.Formatted()] {
+			existing = append(existing, date.Formatted())
+		}
+	}
+	clearSelection(user.ID)
+	return
+}
+
+// buildSelectMessage is the month grid where the organizer selects the days to add
+func buildSelectMessage(lang Language, c *Calendar, userID int64, date Date) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		month    = lang.Format(date.Time, "January 2006")
+		today    = Now().DayStart()
+		blocked  = alertCaller(BLOCK, "", lang.T("alert.day_blocked"))
+		selected = len(selections[userID])
+	)
+	text = lang.Plural("select.title", selected, month)
+
+	date = date.MonthStart()
+	kbd = genMonthGrid(lang, userID, date, []tgui.InlineButton{
+		tgui.InlineCaller("⏮", "/publish", string(date.Skip(0, -1, 0).Formatted()), SELECT_VIEW),
+		alertCaller("", month, month),
+		tgui.InlineCaller("⏭", "/publish", string(date.Skip(0, 1, 0).Formatted()), SELECT_VIEW),
+	}, func(day Date) tgui.InlineButton {
+		var label = fmt.Sprint(day.Day())
+		if c != nil && len(c.DatesOn(day)) > 0 {
+			label += string(EVENT)
+		}
+
+		switch {
+		case day.DayStart().IsBefore(today):
+			return blocked
+		case selections[userID][day.DayStart().Formatted()]:
+			return tgui.InlineCaller(DONE.Text(label), "/publish", string(day.Formatted()), SELECT_DAY)
+		default:
+			return tgui.InlineCaller(label, "/publish", string(day.Formatted()), SELECT_DAY)
+		}
+	})
+
+	if selected > 0 {
+		kbd = append(kbd, []tgui.InlineButton{
+			tgui.InlineCaller("🕒 "+lang.T("btn.pick_time"), "/publish", string(date.Formatted()), HOUR_VIEW),
+			tgui.InlineCaller("🧹 "+lang.T("btn.clear"), "/publish", string(date.Formatted()), SELECT_CLEAR),
+		})
+	}
+	return CALENDAR.Text(text), append(kbd, []tgui.InlineButton{
+		backButton(lang, "/publish", string(date.Formatted()), MONTH_VIEW),
+		cancelButton(lang),
+	})
+}
+
+// buildHourMessage proposes the hours of the day for the selected days
+func buildHourMessage(lang Language, userID int64, date Date) (text string, kbd [][]tgui.InlineButton) {
+	var hours = make([]tgui.InlineButton, 24)
+	for hour := range hours {
+		at := Parse(time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, date.Location()))
+		hours[hour] = tgui.InlineCaller(at.Format("15"), "/publish", string(at.Formatted()), MINUTE_VIEW)
+	}
+
+	return "🕒 " + lang.Plural("select.hour", len(selections[userID])), append(tgui.Arrange(6, hours...), []tgui.InlineButton{
+		backButton(lang, "/publish", string(date.Formatted()), SELECT_VIEW),
+		cancelButton(lang),
+	})
+}
+
+// buildMinuteMessage proposes the minutes of the chosen hour for the selected days
+func buildMinuteMessage(lang Language, date Date) (text string, kbd [][]tgui.InlineButton) {
+	var row []tgui.InlineButton
+	for _, minute := range BULK_MINUTES {
+		at := Parse(time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), minute, 0, 0, date.Location()))
+		row = append(row, tgui.InlineCaller(at.Format("15:04"), "/publish", string(at.Formatted()), BULK_REVIEW))
+	}
+
+	return "🕒 " + lang.T("select.minute", date.Format("15")), [][]tgui.InlineButton{row, {
+		backButton(lang, "/publish", string(date.Formatted()), HOUR_VIEW),
+		cancelButton(lang),
+	}}
+}
+
+// buildBulkReview lists the dates that will be added to the calendar asking for confirmation
+func buildBulkReview(lang Language, c *Calendar, userID int64, at Date) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		dates = atTime(SelectedDays(userID), at)
+		now   = Now()
+	)
+	text = lang.Plural("select.review", len(dates), at.Format("15:04"))
+	for _, date := range dates {
+		switch {
+		case date.IsBefore(now):
+			text += "\n🚫 <s>" + date.Beautify(lang) + "</s>"
+		case c != nil && c.dates[date.Formatted()] != nil:
+			text += "\n" + EVENT.Text(date.Beautify(lang)) + " " + lang.T("select.existing")
+		default:
+			text += "\n🕒 " + date.Beautify(lang)
+		}
+	}
+
+	return CALENDAR.Text(text), [][]tgui.InlineButton{
+		{tgui.InlineCaller(CONFIRM.Text(lang.T("btn.confirm")), "/publish", string(at.Formatted()), BULK_ADD)},
+		{
+			tgui.InlineCaller("🕒 "+lang.T("btn.pick_time"), "/publish", string(at.Formatted()), HOUR_VIEW),
+			backButton(lang, "/publish", string(at.Formatted()), SELECT_VIEW),
+		},
+		tgui.Wrap(cancelButton(lang)),
+	}
+}
+
+// buildBulkSummary tells the organizer which of the selected dates have been added,
+// which already were in the calendar and which have been skipped because already passed
+func buildBulkSummary(lang Language, c Calendar, added, existing, passed []FormattedDate) message.Text {
+	var text = lang.Plural("select.added", len(added))
+	for _, date := range added {
+		text += "\n🕒 " + c.Describe(date, lang)
+	}
+	if len(existing) > 0 {
+		text += lang.Plural("select.already", len(existing))
+		for _, date := range existing {
+			text += "\n" + EVENT.Text(c.Describe(date, lang))
+		}
+	}
+	if len(passed) > 0 {
+		text += lang.Plural("select.passed", len(passed))
+		for _, date := range passed {
+			text += "\n🚫 " + date.Beautify(lang)
+		}
+	}
+
+	return genDefaultMessage(DONE, text, []tgui.InlineButton{
+		backButton(lang, "/publish", string(Now().Formatted()), MONTH_VIEW),
+		closeButton(lang),
+	})
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- CALDAV CREDENTIALS --- */
+
+// caldavPasswords are the hashes of the CalDAV password of each user
+var caldavPasswords = map[int64]string{}
+
+// IssueCalDAVPassword creates a new CalDAV password for the user, replacing the previous one
+func IssueCalDAVPassword(userID int64) string {
+	var password = randomHex(12)
+	caldavPasswords[userID] = hashToken(password)
+	return password
+}
+
+// RevokeCalDAVPassword deletes the CalDAV password of the user, if any
+func RevokeCalDAVPassword(userID int64) (revoked bool) {
+	_, revoked = caldavPasswords[userID]
+	delete(caldavPasswords, userID)
+	return
+}
+
+// CalDAVURL returns the address to give to the CalDAV clients
+func CalDAVURL() string {
+	return strings.TrimRight(config.PublicURL, "/") + "/caldav/"
+}
+
+/* --- CALDAV OBJECTS --- */
+
+// caldavObject is an event created by a CalDAV client, that chose its name and UID
+type caldavObject struct {
+	Date FormattedDate `json:"date"`
+	UID  string        `json:"uid"`
+}
+
+// caldavObjects are the events created by CalDAV clients of each organizer, by resource name
+var caldavObjects = map[int64]map[string]caldavObject{}
+
+// davEvent is an event of the calendar as seen by the CalDAV clients
+type davEvent struct {
+	name string // name of the resource
+	uid  string
+	date FormattedDate
+}
+
+// davEvents lists all the events of the calendar of a user as CalDAV resources
+func davEvents(userID int64, c Calendar) (events []davEvent) {
+	var named = map[FormattedDate]davEvent{}
+	for name, object := range caldavObjects[userID] {
+		if c.dates[object.Date] != nil {
+			named[object.Date] = davEvent{name, object.UID, object.Date}
+		} else {
+			delete(caldavObjects[userID], name)
+		}
+	}
+
+	for _, date := range c.SortedDates() {
+		event, ok := named[date]
+		if !ok {
+			parsed, err := date.Parse()
+			if err != nil {
+				continue
+			}
+			event = davEvent{parsed.UTC().Format("20060102T1504") + ".ics", eventUID(c, parsed), date}
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+// davEventNamed returns the event of the calendar having the given resource name
+func davEventNamed(userID int64, c Calendar, name string) *davEvent {
+	for _, event := range davEvents(userID, c) {
+		if event.name == name {
+			return &event
+		}
+	}
+	return nil
+}
+
+// etag identifies the version of an event, it changes when the event content does
+func (e davEvent) etag(c Calendar) string {
+	var sum = sha256.Sum256([]byte(fmt.Sprint(e.uid, e.date, c.name, c.description, c.CountAttendee(e.date))))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// content returns the event in the iCalendar format, without METHOD as required by CalDAV
+func (e davEvent) content(c Calendar) string {
+	var feed = buildFeed(c.name, c.description, []string{buildFeedEvent(c, e.date, e.uid)})
+	return strings.Replace(feed, "METHOD:PUBLISH\r\n", "", 1)
+}
+
+/* --- CALDAV SERVER --- */
+
+const (
+	DAV_HEADER = `<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`
+	DAV_FOOTER = `</d:multistatus>`
+)
+
+// serveCalDAV handles /caldav/, /caldav/{user}/, /caldav/{user}/calendar/ and
+// /caldav/{user}/calendar/{event}.ics
+func serveCalDAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+		return
+	}
+
+	// the body is read before locking the data to not wait for slow clients
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	username, password, ok := r.BasicAuth()
+	userID, err := strconv.ParseInt(username, 10, 64)
+
+	dataLock.Lock()
+	defer dataLock.Unlock()
+
+	if hash, exists := caldavPasswords[userID]; !ok || err != nil || !exists || subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(password))) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="CalenDaggerbill"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var parts = strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/caldav"), "/"), "/")
+	if parts[0] == "" {
+		serveDAVRoot(w, r, userID)
+		return
+	}
+	if parts[0] != strconv.FormatInt(userID, 10) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		serveDAVHome(w, r, userID)
+	case len(parts) == 2 && parts[1] == "calendar":
+		serveDAVCollection(w, r, userID, body)
+	case len(parts) == 3 && parts[1] == "calendar" && strings.HasSuffix(parts[2], ".ics"):
+		serveDAVObject(w, r, userID, parts[2], body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func serveDAVRoot(w http.ResponseWriter, r *http.Request, userID int64) {
+	if r.Method != "PROPFIND" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeMultistatus(w, davResponse("/caldav/", principalProps(userID)...))
+}
+
+func serveDAVHome(w http.ResponseWriter, r *http.Request, userID int64) {
+	if r.Method != "PROPFIND" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var responses = []string{davResponse(homeHref(userID), principalProps(userID)...)}
+	if r.Header.Get("Depth") != "0" {
+		responses = append(responses, davResponse(collectionHref(userID), collectionProps(userID)...))
+	}
+	writeMultistatus(w, responses...)
+}
+
+func serveDAVCollection(w http.ResponseWriter, r *http.Request, userID int64, body []byte) {
+	var calendar = CalendarOf(userID)
+
+	switch r.Method {
+	case "PROPFIND":
+		var responses = []string{davResponse(collectionHref(userID), collectionProps(userID)...)}
+		if calendar != nil && r.Header.Get("Depth") == "1" {
+			for _, event := range davEvents(userID, *calendar) {
+				responses = append(responses, davResponse(collectionHref(userID)+event.name,
+					"<d:getetag>"+escapeXML(event.etag(*calendar))+"</d:getetag>",
+					"<d:getcontenttype>text/calendar; charset=utf-8; component=vevent</d:getcontenttype>",
+					"<d:resourcetype/>",
+				))
+			}
+		}
+		writeMultistatus(w, responses...)
+
+	case "REPORT":
+		var events []davEvent
+		if calendar != nil {
+			events = davEvents(userID, *calendar)
+		}
+
+		// calendar-multiget asks only some of the events, calendar-query all of them
+		if hrefs := extractHrefs(body); len(hrefs) > 0 {
+			var requested []davEvent
+			for _, event := range events {
+				if hrefs[collectionHref(userID)+event.name] || hrefs[event.name] {
+					requested = append(requested, event)
+				}
+			}
+			events = requested
+		}
+
+		var responses []string
+		for _, event := range events {
+			responses = append(responses, davResponse(collectionHref(userID)+event.name,
+				"<d:getetag>"+escapeXML(event.etag(*calendar))+"</d:getetag>",
+				"<c:calendar-data>"+escapeXML(event.content(*calendar))+"</c:calendar-data>",
+			))
+		}
+		writeMultistatus(w, responses...)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func serveDAVObject(w http.ResponseWriter, r *http.Request, userID int64, name string, body []byte) {
+	var (
+		calendar = CalendarOf(userID)
+		existing *davEvent
+	)
+	if calendar != nil {
+		existing = davEventNamed(userID, *calendar, name)
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if existing == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", existing.etag(*calendar))
+		io.WriteString(w, existing.content(*calendar))
+
+	case http.MethodPut:
+		if calendar == nil {
+			http.Error(w, "create your calendar from the bot first", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && existing != nil {
+			http.Error(w, "event already exists", http.StatusPreconditionFailed)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && (existing == nil || match != existing.etag(*calendar)) {
+			http.Error(w, "event changed", http.StatusPreconditionFailed)
+			return
+		}
+
+		start, days, uid, err := parseICalEvent(strings.NewReader(string(body)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var date = Parse(start.In(config.location))
+		if days > 0 {
+			date = date.DayStart()
+		}
+		if !date.IsAfter(Now()) && (days == 0 || date.IsBefore(Now().DayStart())) {
+			http.Error(w, "the date has already passed", http.StatusForbidden)
+			return
+		}
+
+		var moved = existing != nil && existing.date != date.Formatted()
+		if (existing == nil || moved) && calendar.dates[date.Formatted()] != nil {
+			http.Error(w, "there is already an event in the given date", http.StatusConflict)
+			return
+		}
+		if moved && calendar.CountAttendee(existing.date) > 0 {
+			http.Error(w, "events with attendees can't be moved", http.StatusForbidden)
+			return
+		}
+
+		status := http.StatusCreated
+		if existing != nil {
+			status = http.StatusNoContent
+		}
+		if moved {
+			RemoveFromCalendar(calendar, existing.date)
+		}
+
+		if days == 0 {
+			AddToCalendar(echotron.User{ID: userID}, date)
+		} else {
+			AddSpanToCalendar(echotron.User{ID: userID}, date, date.Skip(0, 0, days-1))
+		}
+		// an event kept in the same date might now cover a different number of days
+		calendar.dates[date.Formatted()].days = days
+		if caldavObjects[userID] == nil {
+			caldavObjects[userID] = map[string]caldavObject{}
+		}
+		if uid == "" {
+			uid = eventUID(*calendar, date)
+		}
+		caldavObjects[userID][name] = caldavObject{Date: date.Formatted(), UID: uid}
+		w.Header().Set("ETag", davEvent{name, uid, date.Formatted()}.etag(*calendar))
+		w.WriteHeader(status)
+
+	case http.MethodDelete:
+		if existing == nil {
+			http.NotFound(w, r)
+			return
+		}
+		RemoveFromCalendar(calendar, existing.date)
+		delete(caldavObjects[userID], name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func homeHref(userID int64) string {
+	return "/caldav/" + strconv.FormatInt(userID, 10) + "/"
+}
+
+func collectionHref(userID int64) string {
+	return homeHref(userID) + "calendar/"
+}
+
+func principalProps(userID int64) []string {
+	return []string{
+		"<d:resourcetype><d:collection/></d:resourcetype>",
+		"<d:current-user-principal><d:href>" + homeHref(userID) + "</d:href></d:current-user-principal>",
+		"<d:principal-URL><d:href>" + homeHref(userID) + "</d:href></d:principal-URL>",
+		"<c:calendar-home-set><d:href>" + homeHref(userID) + "</d:href></c:calendar-home-set>",
+	}
+}
+
+func collectionProps(userID int64) []string {
+	var (
+		name = LanguageOf(userID).T("calendar.default_name", userID)
+		ctag = sha256.New()
+	)
+	if calendar := CalendarOf(userID); calendar != nil {
+		name = calendar.name
+		for _, event := range davEvents(userID, *calendar) {
+			io.WriteString(ctag, event.name+event.etag(*calendar))
+		}
+	}
+
+	return []string{
+		"<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>",
+		"<d:displayname>" + escapeXML(name) + "</d:displayname>",
+		"<cs:getctag>" + hex.EncodeToString(ctag.Sum(nil)[:8]) + "</cs:getctag>",
+		`<c:supported-calendar-component-set><c:comp name="VEVENT"/></c:supported-calendar-component-set>`,
+		"<d:current-user-privilege-set><d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege></d:current-user-privilege-set>",
+	}
+}
+
+// davResponse is the response of a single resource of a multistatus
+func davResponse(href string, props ...string) string {
+	return "<d:response><d:href>" + escapeXML(href) + "</d:href><d:propstat><d:prop>" +
+		strings.Join(props, "") +
+		"</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>"
+}
+
+func writeMultistatus(w http.ResponseWriter, responses ...string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, DAV_HEADER+strings.Join(responses, "")+DAV_FOOTER)
+}
+
+func escapeXML(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
+
+// extractHrefs grabs all the hrefs contained in a request
+func extractHrefs(body []byte) map[string]bool {
+	var (
+		hrefs   = map[string]bool{}
+		decoder = xml.NewDecoder(strings.NewReader(string(body)))
+		inHref  bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return hrefs
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			inHref = t.Name.Local == "href"
+		case xml.EndElement:
+			inHref = false
+		case xml.CharData:
+			if inHref {
+				href := strings.TrimSpace(string(t))
+				hrefs[href], hrefs[path.Base(href)] = true, true
+			}
+		}
+	}
+}
+
+/* --- ICALENDAR PARSING --- */
+
+var icalDateRgx = regexp.MustCompile(`^(\d{8})(T(\d{6})(Z)?)?$`)
+
+// parseICalEvent grabs start, covered days and UID of the first event of an iCalendar,
+// days is 0 for the events that have a time
+func parseICalEvent(source io.Reader) (start time.Time, days int, uid string, err error) {
+	var (
+		scanner = bufio.NewScanner(source)
+		lines   []string
+		inEvent bool
+		found   bool
+		end     time.Time
+	)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	for _, line := range lines {
+		var ind = strings.IndexByte(line, ':')
+		if ind < 0 {
+			continue
+		}
+		var (
+			params = strings.Split(line[:ind], ";")
+			value  = line[ind+1:]
+		)
+
+		switch strings.ToUpper(params[0]) {
+		case "BEGIN":
+			inEvent = inEvent || strings.EqualFold(value, "VEVENT")
+		case "END":
+			if inEvent && strings.EqualFold(value, "VEVENT") {
+				if !found {
+					return start, 0, uid, fmt.Errorf("missing DTSTART")
+				}
+				if days > 0 && end.After(start) { // DTEND of the all-day events is exclusive
+					days = int(end.Sub(start).Hours()/24 + 0.5)
+				}
+				return
+			}
+		case "UID":
+			if inEvent {
+				uid = value
+			}
+		case "DTSTART":
+			if !inEvent {
+				continue
+			}
+			var allDay bool
+			if start, allDay, err = parseICalTime(params, value); err != nil {
+				return
+			}
+			if found, days = true, 0; allDay {
+				days = 1
+			}
+		case "DTEND":
+			if !inEvent {
+				continue
+			}
+			if end, _, err = parseICalTime(params, value); err != nil {
+				return
+			}
+		}
+	}
+	return start, 0, uid, fmt.Errorf("missing VEVENT")
+}
+
+// parseICalTime parses the value of a DTSTART or DTEND, telling if it is a whole day
+// (VALUE=DATE), the dates without time zone are in the bot's one
+func parseICalTime(params []string, value string) (t time.Time, allDay bool, err error) {
+	var (
+		match    = icalDateRgx.FindStringSubmatch(value)
+		location = config.location
+	)
+	if match == nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s %q", strings.ToUpper(params[0]), value)
+	}
+	for _, param := range params[1:] {
+		if tzid := strings.TrimPrefix(param, "TZID="); tzid != param {
+			if loc, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				location = loc
+			}
+		}
+	}
+
+	switch {
+	case match[4] == "Z":
+		t, err = time.Parse("20060102T150405Z", value)
+	case match[2] != "":
+		t, err = time.ParseInLocation("20060102T150405", value, location)
+	default: // all-day events starts at midnight
+		t, err = time.ParseInLocation("20060102", value, location)
+		allDay = true
+	}
+	return
+}
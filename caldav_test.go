@@ -0,0 +1,181 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* --- CALDAV --- */
+
+// newTestCalDAV resets the bot and the CalDAV data, creating an empty calendar
+// for the organizer, and returns its CalDAV password
+func newTestCalDAV(t *testing.T) (*FakeTelegram, *Calendar, string) {
+	t.Helper()
+
+	fake, _ := newTestBot(t)
+	caldavPasswords = map[int64]string{}
+	caldavObjects = map[int64]map[string]caldavObject{}
+	return fake, AddToCalendar(organizer), IssueCalDAVPassword(organizer.ID)
+}
+
+// davRequest serves a request to the event of the organizer's calendar with the
+// given resource name, headers are given as name and value pairs
+func davRequest(method, name, password, body string, headers ...string) *httptest.ResponseRecorder {
+	var (
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(method, collectionHref(organizer.ID)+name, strings.NewReader(body))
+	)
+	r.SetBasicAuth(strconv.FormatInt(organizer.ID, 10), password)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	serveCalDAV(w, r)
+	return w
+}
+
+// icalEvent builds an iCalendar with a single event, properties are given
+// already formatted like "DTSTART;VALUE=DATE:20300115"
+func icalEvent(properties ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\n" +
+		strings.Join(properties, "\r\n") +
+		"\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+}
+
+// assertStatus checks the status code of the response
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+	if w.Code != expected {
+		t.Fatalf("status %d, expected %d: %s", w.Code, expected, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+// dateAt returns the date of the calendar at the given time of January 2030
+func dateAt(day, hour int) FormattedDate {
+	return Parse(time.Date(2030, time.January, day, hour, 0, 0, 0, time.UTC)).Formatted()
+}
+
+func TestCalDAVUnauthorized(t *testing.T) {
+	newTestCalDAV(t)
+
+	for _, password := range []string{"", "wrong"} {
+		w := davRequest("PUT", "a.ics", password, icalEvent("DTSTART:20300115T180000Z"))
+		assertStatus(t, w, http.StatusUnauthorized)
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatal("the client is not asked for the credentials")
+		}
+	}
+
+	// the password of a user can't be used for the calendar of another one
+	var (
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("PROPFIND", collectionHref(organizer.ID), nil)
+	)
+	r.SetBasicAuth(strconv.FormatInt(invitee.ID, 10), IssueCalDAVPassword(invitee.ID))
+	serveCalDAV(w, r)
+	assertStatus(t, w, http.StatusForbidden)
+
+	if len(CalendarOf(organizer.ID).dates) != 0 {
+		t.Fatal("an unauthorized request changed the calendar")
+	}
+}
+
+func TestCalDAVCreate(t *testing.T) {
+	_, calendar, password := newTestCalDAV(t)
+
+	var event = icalEvent("UID:party@example.com", "DTSTART:20300115T180000Z", "DTEND:20300115T200000Z")
+	w := davRequest("PUT", "party.ics", password, event, "If-None-Match", "*")
+	assertStatus(t, w, http.StatusCreated)
+	if data := calendar.dates[dateAt(15, 18)]; data == nil || data.days != 0 {
+		t.Fatalf("the event has not been created at the given time: %+v", calendar.dates)
+	}
+	var etag = w.Header().Get("ETag")
+
+	w = davRequest("GET", "party.ics", password, "")
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("ETag") != etag || !strings.Contains(w.Body.String(), "UID:party@example.com") {
+		t.Fatalf("unexpected event with ETag %s:\n%s", w.Header().Get("ETag"), w.Body.String())
+	}
+
+	// the resource can be created only once
+	assertStatus(t, davRequest("PUT", "party.ics", password, event, "If-None-Match", "*"), http.StatusPreconditionFailed)
+	// and updated only if the client has the last version
+	assertStatus(t, davRequest("PUT", "party.ics", password, event, "If-Match", `"outdated"`), http.StatusPreconditionFailed)
+	assertStatus(t, davRequest("PUT", "party.ics", password, event, "If-Match", etag), http.StatusNoContent)
+
+	// another resource can't take an already used date
+	assertStatus(t, davRequest("PUT", "other.ics", password, icalEvent("DTSTART:20300115T180000Z")), http.StatusConflict)
+	if len(calendar.dates) != 1 {
+		t.Fatalf("%d events in the calendar, expected 1", len(calendar.dates))
+	}
+
+	assertStatus(t, davRequest("DELETE", "party.ics", password, ""), http.StatusNoContent)
+	if len(calendar.dates) != 0 {
+		t.Fatal("the event has not been deleted")
+	}
+}
+
+func TestCalDAVMove(t *testing.T) {
+	fake, calendar, password := newTestCalDAV(t)
+
+	assertStatus(t, davRequest("PUT", "party.ics", password, icalEvent("DTSTART:20300115T180000Z")), http.StatusCreated)
+	assertStatus(t, davRequest("PUT", "party.ics", password, icalEvent("DTSTART:20300116T180000Z")), http.StatusNoContent)
+	if calendar.dates[dateAt(15, 18)] != nil || calendar.dates[dateAt(16, 18)] == nil {
+		t.Fatalf("the event has not been moved: %+v", calendar.dates)
+	}
+
+	// events with attendees stay where they are
+	join(t, fake, calendar, dateAt(16, 18))
+	assertStatus(t, davRequest("PUT", "party.ics", password, icalEvent("DTSTART:20300117T180000Z")), http.StatusForbidden)
+	if calendar.dates[dateAt(17, 18)] != nil || !calendar.dates[dateAt(16, 18)].hasJoined(invitee.ID) {
+		t.Fatalf("the event with attendees has been moved: %+v", calendar.dates)
+	}
+}
+
+func TestCalDAVAllDay(t *testing.T) {
+	_, calendar, password := newTestCalDAV(t)
+
+	// DTEND of the all-day events is the day after the last one
+	var span = icalEvent("DTSTART;VALUE=DATE:20300115", "DTEND;VALUE=DATE:20300118")
+	assertStatus(t, davRequest("PUT", "trip.ics", password, span), http.StatusCreated)
+	if data := calendar.dates[dateAt(15, 0)]; data == nil || data.days != 3 {
+		t.Fatalf("the event does not cover 3 days: %+v", calendar.dates)
+	}
+
+	// without DTEND the event covers its day only
+	assertStatus(t, davRequest("PUT", "day.ics", password, icalEvent("DTSTART;VALUE=DATE:20300120")), http.StatusCreated)
+	if data := calendar.dates[dateAt(20, 0)]; data == nil || data.days != 1 {
+		t.Fatalf("the event does not cover a single day: %+v", calendar.dates)
+	}
+
+	// shortening the span keeps the event in the same date
+	span = icalEvent("DTSTART;VALUE=DATE:20300115", "DTEND;VALUE=DATE:20300116")
+	assertStatus(t, davRequest("PUT", "trip.ics", password, span), http.StatusNoContent)
+	if data := calendar.dates[dateAt(15, 0)]; data == nil || data.days != 1 {
+		t.Fatalf("the event has not been shortened: %+v", calendar.dates)
+	}
+}
+
+func TestCalDAVPastDates(t *testing.T) {
+	_, calendar, password := newTestCalDAV(t)
+
+	for _, event := range []string{
+		icalEvent("DTSTART:20300110T110000Z"),                // an hour ago
+		icalEvent("DTSTART;VALUE=DATE:20300109"),             // yesterday
+		icalEvent("DTSTART:20290115T180000Z"),                // last year
+		icalEvent("DTSTART;TZID=Asia/Tokyo:20300110T200000"), // 11:00 UTC
+	} {
+		assertStatus(t, davRequest("PUT", "past.ics", password, event), http.StatusForbidden)
+	}
+	if len(calendar.dates) != 0 {
+		t.Fatalf("past events have been created: %+v", calendar.dates)
+	}
+
+	// today is not over yet for the all-day events
+	assertStatus(t, davRequest("PUT", "today.ics", password, icalEvent("DTSTART;VALUE=DATE:20300110")), http.StatusCreated)
+	// and neither are the next hours for the others
+	assertStatus(t, davRequest("PUT", "tonight.ics", password, icalEvent("DTSTART:20300110T200000Z")), http.StatusCreated)
+}
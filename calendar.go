@@ -1,33 +1,65 @@
 package main
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 /* --- CALENDAR ERRORS --- */
 
+// CalendarError is the key of the catalog's message describing the error
 type CalendarError string
 
 func (e CalendarError) Error() string {
-	return string(e)
+	return DEFAULT_LANGUAGE.T(string(e))
 }
 
 const (
-	INVALID_CALENDAR CalendarError = "Empty calendar, invitation might be expired"
-	INVALID_EVENT    CalendarError = "This date is not avaiable anymore"
-	ALREADY_JOINED   CalendarError = "Event already joined"
+	INVALID_CALENDAR   CalendarError = "error.invalid_calendar"
+	INVALID_EVENT      CalendarError = "error.invalid_event"
+	ALREADY_JOINED     CalendarError = "error.already_joined"
+	NOT_JOINED         CalendarError = "error.not_joined"
+	EVENT_FULL         CalendarError = "error.event_full"
+	INVALID_INVITATION CalendarError = "error.invalid_invitation"
+	INVALID_FIELD      CalendarError = "error.invalid_field"
+	INVALID_VALUE      CalendarError = "error.invalid_value"
+	INVALID_WEBHOOK    CalendarError = "error.invalid_webhook"
+	TOO_MANY_WEBHOOKS  CalendarError = "error.too_many_webhooks"
+	PRIVATE_WEBHOOK    CalendarError = "error.private_webhook"
+	INVALID_RATING     CalendarError = "error.invalid_rating"
+	NOT_RATED          CalendarError = "error.not_rated"
+	INVALID_CODE       CalendarError = "error.invalid_code"
+	ALREADY_CHECKED_IN CalendarError = "error.already_checked_in"
+	CHECKIN_CLOSED     CalendarError = "error.checkin_closed"
+	OVERLAPPING        CalendarError = "error.overlapping"
+	INVALID_TEMPLATE   CalendarError = "error.invalid_template"
+	TOO_MANY_TEMPLATES CalendarError = "error.too_many_templates"
+	INVALID_CHANGE     CalendarError = "error.invalid_change"
+	NOT_REVERSIBLE     CalendarError = "error.not_reversible"
 )
 
 /* --- CALENDAR --- */
 
+// Length of the events of the calendars that did not set it
+const DEFAULT_EVENT_DURATION = time.Hour
+
 type Calendar struct {
 	notification toggler
 	name         string
 	description  string
 	invitation   string
+	capacity     int           // max attendee per event, 0 means unlimited
+	duration     time.Duration // length of the events, 0 means DEFAULT_EVENT_DURATION
+	feed         string        // token of the iCalendar feed, empty if never requested
 	lastTimeUsed Date
 	dates        map[FormattedDate]*Event
+	archive      map[FormattedDate]*Event // events that already occurred
+	joins        []time.Time              // when each join happened, for the statistics
+	visitors     map[int64]int            // how many times each user opened the invitation links
+	templates    []Template               // shapes of the events saved by the organizer
+	changes      []Change                 // log of the last changes, the most recent last
 }
 
 func NewCalendar(name, description, invitation string) *Calendar {
@@ -50,6 +82,14 @@ func (c *Calendar) addDate(date FormattedDate) (confirm bool) {
 	return
 }
 
+// addSpan adds an all-day event that covers the given number of days from the date
+func (c *Calendar) addSpan(date FormattedDate, days int) (confirm bool) {
+	if confirm = c.addDate(date); confirm {
+		c.dates[date].days = days
+	}
+	return
+}
+
 func (c *Calendar) removeDate(date FormattedDate) (deleted *Event) {
 	c.lastTimeUsed = Now()
 
@@ -60,6 +100,27 @@ func (c *Calendar) removeDate(date FormattedDate) (deleted *Event) {
 	return
 }
 
+// event returns the event in the given date, even if it already occurred
+func (c Calendar) event(date FormattedDate) *Event {
+	if event := c.dates[date]; event != nil {
+		return event
+	}
+	return c.archive[date]
+}
+
+// archiveDate moves an event that occurred from the dates to the archive
+func (c *Calendar) archiveDate(date FormattedDate) (archived *Event) {
+	archived = c.dates[date]
+	if archived != nil {
+		delete(c.dates, date)
+		if c.archive == nil {
+			c.archive = make(map[FormattedDate]*Event)
+		}
+		c.archive[date] = archived
+	}
+	return
+}
+
 func (c *Calendar) joinDate(date FormattedDate, userID int64) error {
 	if c == nil {
 		return INVALID_CALENDAR
@@ -73,8 +134,36 @@ func (c *Calendar) joinDate(date FormattedDate, userID int64) error {
 	if event.hasJoined(userID) {
 		return ALREADY_JOINED
 	}
+	if !c.HasFreeSeats(date) {
+		return EVENT_FULL
+	}
 
 	event.join(userID)
+	c.joins = append(c.joins, clock.Now())
+	return nil
+}
+
+// visit counts the opening of an invitation link by a user
+func (c *Calendar) visit(userID int64) {
+	if c.visitors == nil {
+		c.visitors = make(map[int64]int)
+	}
+	c.visitors[userID]++
+}
+
+func (c *Calendar) leaveDate(date FormattedDate, userID int64) error {
+	if c == nil {
+		return INVALID_CALENDAR
+	}
+	c.lastTimeUsed = Now()
+
+	var event = c.dates[date]
+	if event == nil {
+		return INVALID_EVENT
+	}
+	if !event.leave(userID) {
+		return NOT_JOINED
+	}
 	return nil
 }
 
@@ -82,8 +171,103 @@ func (c Calendar) CountAttendee(forDate FormattedDate) int {
 	return len(c.CurrentAttendee(forDate))
 }
 
+// HasFreeSeats tells if someone else can still join the event in the given date
+func (c Calendar) HasFreeSeats(forDate FormattedDate) bool {
+	return c.capacity <= 0 || c.CountAttendee(forDate) < c.capacity
+}
+
+// Capacity returns a human readable value of the calendar's capacity
+func (c Calendar) Capacity(lang Language) string {
+	if c.capacity <= 0 {
+		return lang.T("capacity.unlimited")
+	}
+	return strconv.Itoa(c.capacity)
+}
+
+// Duration returns the length of the events of the calendar
+func (c Calendar) Duration() time.Duration {
+	if c.duration <= 0 {
+		return DEFAULT_EVENT_DURATION
+	}
+	return c.duration
+}
+
+// Interval returns when the event in the given date starts and ends,
+// all-day events go from the midnight of the first day to the one after the last
+func (c Calendar) Interval(date FormattedDate) (start, end time.Time, err error) {
+	parsed, err := date.Parse()
+	if err != nil {
+		return
+	}
+	if days := c.Days(date); days > 0 {
+		start = parsed.DayStart().Time
+		return start, start.AddDate(0, 0, days), nil
+	}
+	return parsed.Time, parsed.Add(c.Duration()), nil
+}
+
+// Days returns how many days the event in the given date covers, 0 if it is not an all-day event
+func (c Calendar) Days(date FormattedDate) int {
+	if event := c.event(date); event != nil {
+		return event.days
+	}
+	return 0
+}
+
+// Occurred returns when the event in the given date is considered past:
+// its start, or the end of the last day for the all-day events
+func (c Calendar) Occurred(date FormattedDate) (time.Time, error) {
+	start, end, err := c.Interval(date)
+	if c.Days(date) > 0 {
+		return end, err
+	}
+	return start, err
+}
+
+// Describe returns a human readable version of the date of an event in the
+// given language, with the days it covers if it lasts all day
+func (c Calendar) Describe(date FormattedDate, lang Language) string {
+	return describeDate(date, c.Days(date), lang)
+}
+
+// describeDate is like Calendar.Describe for an event covering the given days
+func describeDate(date FormattedDate, days int, lang Language) string {
+	var parsed, err = date.Parse()
+	switch {
+	case err != nil || days == 0:
+		return date.Beautify(lang)
+	case days == 1:
+		return lang.T("date.all_day", lang.Format(parsed.Time, DAY_FORMAT))
+	default:
+		return lang.T("date.span", lang.Format(parsed.Time, DAY_FORMAT), lang.Format(parsed.AddDate(0, 0, days-1), DAY_FORMAT))
+	}
+}
+
+// Overlaps tells if the event in the given date overlaps the one of another calendar
+func (c Calendar) Overlaps(date FormattedDate, other Calendar, otherDate FormattedDate) bool {
+	start, end, err := c.Interval(date)
+	if err != nil {
+		return false
+	}
+	otherStart, otherEnd, err := other.Interval(otherDate)
+	return err == nil && start.Before(otherEnd) && otherStart.Before(end)
+}
+
+// Overlapping returns the other dates of the calendar overlapping the given one
+func (c Calendar) Overlapping(date FormattedDate) (dates []FormattedDate) {
+	for _, other := range c.SortedDates() {
+		if other != date && c.Overlaps(date, c, other) {
+			dates = append(dates, other)
+		}
+	}
+	return
+}
+
 func (c Calendar) CurrentAttendee(forDate FormattedDate) []int64 {
-	return c.dates[forDate].attendee
+	if event := c.dates[forDate]; event != nil {
+		return event.attendee
+	}
+	return nil
 }
 
 func (c Calendar) AllCurrentAttendee() []int64 {
@@ -103,24 +287,192 @@ func (c Calendar) AllCurrentAttendee() []int64 {
 	return attendee
 }
 
+// DatesOn returns, in chronological order, the dates of the calendar in the
+// same day of the given one, including the all-day events covering it
+func (c Calendar) DatesOn(day Date) (dates []FormattedDate) {
+	var midnight = day.DayStart().Time
+	for _, date := range c.SortedDates() {
+		if c.dates[date].days > 0 {
+			if start, end, err := c.Interval(date); err == nil && !midnight.Before(start) && midnight.Before(end) {
+				dates = append(dates, date)
+			}
+		} else if parsed, err := date.Parse(); err == nil && parsed.IsSameDay(day) {
+			dates = append(dates, date)
+		}
+	}
+	return
+}
+
+// SortedDates returns all the dates of the calendar in chronological order
+func (c Calendar) SortedDates() []FormattedDate {
+	var dates = make([]FormattedDate, 0, len(c.dates))
+	for date := range c.dates {
+		dates = append(dates, date)
+	}
+	SortDates(dates)
+	return dates
+}
+
+// SortedArchive returns the archived dates of the calendar, most recent first
+func (c Calendar) SortedArchive() []FormattedDate {
+	var dates = make([]FormattedDate, 0, len(c.archive))
+	for date := range c.archive {
+		dates = append(dates, date)
+	}
+	SortDates(dates)
+	for i, j := 0, len(dates)-1; i < j; i, j = i+1, j-1 {
+		dates[i], dates[j] = dates[j], dates[i]
+	}
+	return dates
+}
+
+// Rating returns the average rating of all the archived events and the number of ratings
+func (c Calendar) Rating() (average float64, count int) {
+	var sum int
+	for _, event := range c.archive {
+		for _, feedback := range event.feedback {
+			sum += feedback.Rating
+			count++
+		}
+	}
+	if count > 0 {
+		average = float64(sum) / float64(count)
+	}
+	return
+}
+
 func (c Calendar) IsUnused(after time.Duration) bool {
-	return len(c.dates) == 0 && time.Since(c.lastTimeUsed.Time) >= after
+	return len(c.dates) == 0 && clock.Now().Sub(c.LastActivity()) >= after
+}
+
+// LastActivity returns when the calendar was last used or one of its events occurred
+func (c Calendar) LastActivity() time.Time {
+	var last = c.lastTimeUsed.Time
+	for date := range c.archive {
+		if parsed, err := date.Parse(); err == nil && parsed.After(last) {
+			last = parsed.Time
+		}
+	}
+	return last
+}
+
+// pruneArchive deletes the archived events occurred and the joins happened before the given time
+func (c *Calendar) pruneArchive(before time.Time) (pruned []FormattedDate) {
+	for len(c.joins) > 0 && c.joins[0].Before(before) {
+		c.joins = c.joins[1:]
+	}
+	for date := range c.archive {
+		if parsed, err := date.Parse(); err != nil || parsed.Before(before) {
+			delete(c.archive, date)
+			pruned = append(pruned, date)
+		}
+	}
+	return
 }
 
 /* --- EVENT --- */
 
 type Event struct {
+	days     int // covered by an all-day event, 0 for the events at a given time
 	attendee []int64
+	attended []int64            // who checked in, also without joining
+	feedback map[int64]Feedback // left by the attendees after the event
+	asked    bool               // if the attendees have been asked for feedback
+}
+
+// Feedback is the rating, from 1 to 5, and the optional comment left by an attendee
+type Feedback struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment,omitempty"`
 }
 
+// Range of the ratings
+const (
+	MIN_RATING = 1
+	MAX_RATING = 5
+)
+
 func (e *Event) join(userID int64) {
 	e.attendee = append(e.attendee, userID)
 }
 
+func (e *Event) leave(userID int64) (left bool) {
+	for i, guestID := range e.attendee {
+		if guestID == userID {
+			e.attendee = append(e.attendee[:i:i], e.attendee[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (e Event) countAttendee() int {
 	return len(e.attendee)
 }
 
+func (e *Event) rate(userID int64, rating int) error {
+	if !e.hasJoined(userID) {
+		return NOT_JOINED
+	}
+	if rating < MIN_RATING || rating > MAX_RATING {
+		return INVALID_RATING
+	}
+	if e.feedback == nil {
+		e.feedback = make(map[int64]Feedback)
+	}
+	e.feedback[userID] = Feedback{Rating: rating, Comment: e.feedback[userID].Comment}
+	return nil
+}
+
+func (e *Event) comment(userID int64, text string) error {
+	var feedback, rated = e.feedback[userID]
+	if !rated {
+		return NOT_RATED
+	}
+	feedback.Comment = text
+	e.feedback[userID] = feedback
+	return nil
+}
+
+// Rating returns the average rating of the event and the number of ratings
+func (e Event) Rating() (average float64, count int) {
+	var sum int
+	for _, feedback := range e.feedback {
+		sum += feedback.Rating
+	}
+	if count = len(e.feedback); count > 0 {
+		average = float64(sum) / float64(count)
+	}
+	return
+}
+
+func (e *Event) attend(userID int64) error {
+	if e.hasAttended(userID) {
+		return ALREADY_CHECKED_IN
+	}
+	e.attended = append(e.attended, userID)
+	return nil
+}
+
+func (e Event) hasAttended(userID int64) bool {
+	for _, guestID := range e.attended {
+		if guestID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// walkIns returns who checked in without joining the event
+func (e Event) walkIns() (users []int64) {
+	for _, userID := range e.attended {
+		if !e.hasJoined(userID) {
+			users = append(users, userID)
+		}
+	}
+	return
+}
+
 func (e Event) hasJoined(userID int64) bool {
 	for _, guestID := range e.attendee {
 		if guestID == userID {
@@ -139,13 +491,33 @@ func Format(t time.Time) FormattedDate {
 }
 
 func Today() FormattedDate {
-	return Format(time.Now())
+	return Format(clock.Now().In(config.location))
 }
 
-func (f FormattedDate) Beautify() string {
+// Beautify returns a human readable version of the date in the given language
+func (f FormattedDate) Beautify(lang Language) string {
+	if date, err := f.Parse(); err == nil {
+		return date.Beautify(lang)
+	}
 	return strings.Replace(string(f), "T", " ", 1)
 }
 
+func (f FormattedDate) Parse() (Date, error) {
+	return ParseDate(string(f))
+}
+
+// SortDates sorts the given dates in chronological order, unparsable ones goes last
+func SortDates(dates []FormattedDate) {
+	sort.SliceStable(dates, func(i, j int) bool {
+		a, errA := dates[i].Parse()
+		b, errB := dates[j].Parse()
+		if errA != nil || errB != nil {
+			return errB != nil && errA == nil
+		}
+		return a.IsBefore(b)
+	})
+}
+
 /* --- DATE --- */
 
 type Date struct {
@@ -154,15 +526,19 @@ type Date struct {
 	time.Time
 }
 
-const DATETIME_FROMAT = "02/01/2006T15:04"
+const (
+	DATETIME_FROMAT   = "02/01/2006T15:04"
+	BEAUTIFIED_FORMAT = "Mon 02 Jan 2006 15:04"
+	DAY_FORMAT        = "Mon 02 Jan 2006"
+)
 
 func Parse(t time.Time) Date {
 	f := Format(t)
-	return Date{f.Beautify(), f, t}
+	return Date{DEFAULT_LANGUAGE.Format(t, BEAUTIFIED_FORMAT), f, t}
 }
 
 func Now() Date {
-	return Parse(time.Now())
+	return Parse(clock.Now().In(config.location))
 }
 
 func ParseDate(source string) (d Date, err error) {
@@ -173,7 +549,7 @@ func ParseDate(source string) (d Date, err error) {
 		return Now().Skip(0, 0, 1), nil
 	}
 
-	date, err := time.Parse(DATETIME_FROMAT, source)
+	date, err := time.ParseInLocation(DATETIME_FROMAT, source, config.location)
 	if err == nil {
 		d = Parse(date)
 	}
@@ -188,20 +564,40 @@ func (d Date) String() string {
 	return d.beautified
 }
 
+// Beautify returns a human readable version of the date in the given language
+func (d Date) Beautify(lang Language) string {
+	return lang.Format(d.Time, BEAUTIFIED_FORMAT)
+}
+
 func (d Date) Week() int {
 	return int(d.Weekday())
 }
 
+// WeekStart returns the first day of the week of the date, given the first weekday
+func (d Date) WeekStart(first time.Weekday) Date {
+	return d.Skip(0, 0, -d.WeekOffset(first))
+}
+
+// WeekOffset returns how many days passed from the start of the week, given the first weekday
+func (d Date) WeekOffset(first time.Weekday) int {
+	return (d.Week() - int(first) + 7) % 7
+}
+
 func (d Date) MonthInfo() (month time.Month, maxDays int) {
 	return d.Month(), d.MonthEnd().Day()
 }
 
+// DayStart returns the midnight of the date
+func (d Date) DayStart() Date {
+	return Parse(time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location()))
+}
+
 func (d Date) MonthStart() Date {
 	return d.Skip(0, 0, -d.Day()+1)
 }
 
 func (d Date) MonthEnd() Date {
-	return d.Skip(0, 1, -d.Day()-1)
+	return d.Skip(0, 1, -d.Day())
 }
 
 func (d Date) Skip(years int, months int, days int) Date {
@@ -216,11 +612,73 @@ func (d Date) IsAfter(date Date) bool {
 	return d.After(date.Time)
 }
 
-func (d Date) WhenOccurrs(do func()) {
-	go func() {
-		<-time.After(d.Sub(time.Now()))
-		do()
-	}()
+func (d Date) IsSameDay(date Date) bool {
+	y1, m1, d1 := d.Date()
+	y2, m2, d2 := date.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+func (d Date) WhenOccurrs(do func()) Timer {
+	return clock.AfterFunc(d.Sub(clock.Now()), do)
+}
+
+/* --- DATE FILTER --- */
+
+// DateFilter is a set of flags used to select only some of the dates of a calendar
+type DateFilter string
+
+const (
+	FILTER_FUTURE rune = 'f' // only dates that has not occurred yet
+	FILTER_JOINED rune = 'j' // only dates joined by the user
+	FILTER_FREE   rune = 's' // only dates with free seats
+)
+
+// ParseDateFilter reads a filter ignoring unknown flags, "-" stands for no filters
+func ParseDateFilter(source string) (f DateFilter) {
+	for _, flag := range []rune{FILTER_FUTURE, FILTER_JOINED, FILTER_FREE} {
+		if strings.ContainsRune(source, flag) {
+			f += DateFilter(flag)
+		}
+	}
+	return
+}
+
+func (f DateFilter) Has(flag rune) bool {
+	return strings.ContainsRune(string(f), flag)
+}
+
+func (f DateFilter) Toggle(flag rune) DateFilter {
+	if f.Has(flag) {
+		return DateFilter(strings.ReplaceAll(string(f), string(flag), ""))
+	}
+	return ParseDateFilter(string(f) + string(flag))
+}
+
+func (f DateFilter) String() string {
+	if f == "" {
+		return "-"
+	}
+	return string(f)
+}
+
+// Apply returns the dates of the given calendar that pass the filter for the user, in chronological order
+func (f DateFilter) Apply(c Calendar, userID int64) (dates []FormattedDate) {
+	var now = Now()
+	for _, date := range c.SortedDates() {
+		if f.Has(FILTER_FUTURE) {
+			if parsed, err := date.Parse(); err != nil || parsed.IsBefore(now) {
+				continue
+			}
+		}
+		if f.Has(FILTER_JOINED) && !c.dates[date].hasJoined(userID) {
+			continue
+		}
+		if f.Has(FILTER_FREE) && !c.HasFreeSeats(date) {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	return
 }
 
 /* --- TOGGLER --- */
@@ -255,9 +713,12 @@ func (t *toggler) Toggle() *toggler {
 
 /* --- UTILITIES --- */
 
+// Repeat calls do every time the given period passes, without blocking
 func Repeat(every time.Duration, do func()) {
-	c := time.Tick(every)
-	for _ = range c {
+	var next func()
+	next = func() {
 		do()
+		clock.AfterFunc(every, next)
 	}
+	clock.AfterFunc(every, next)
 }
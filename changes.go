@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- CHANGES --- */
+
+// Max number of changes kept in the log of each calendar
+const MAX_CHANGES = 100
+
+// Kinds of the changes of a calendar
+const (
+	CHANGE_EDITED  = "edited"  // a field has been edited
+	CHANGE_ADDED   = "added"   // a date has been added
+	CHANGE_REMOVED = "removed" // a date has been removed with its attendees
+	CHANGE_JOINED  = "joined"  // an attendee joined a date
+	CHANGE_LEFT    = "left"    // an attendee left a date
+)
+
+// Change is an entry of the log of a calendar, telling who changed what and when
+type Change struct {
+	ID       int           `json:"id"`
+	Kind     string        `json:"kind"`
+	Actor    int64         `json:"actor"`
+	At       time.Time     `json:"at"`
+	Date     FormattedDate `json:"date,omitempty"`
+	Days     int           `json:"days,omitempty"` // covered by the all-day events
+	Field    string        `json:"field,omitempty"`
+	Previous string        `json:"previous,omitempty"`
+	Value    string        `json:"value,omitempty"`
+	UserID   int64         `json:"user_id,omitempty"` // attendee who joined or left
+	Event    *Event        `json:"event,omitempty"`   // removed, to restore it
+	Undone   bool          `json:"undone,omitempty"`
+}
+
+// record adds a change to the log of the calendar, forgetting the oldest ones
+func (c *Calendar) record(change Change) {
+	change.ID, change.At = 1, clock.Now()
+	if last := len(c.changes) - 1; last >= 0 {
+		change.ID = c.changes[last].ID + 1
+	}
+	c.changes = append(c.changes, change)
+	if len(c.changes) > MAX_CHANGES {
+		c.changes = append(c.changes[:0:0], c.changes[len(c.changes)-MAX_CHANGES:]...)
+	}
+}
+
+// change returns the change of the log with the given ID, nil if forgotten
+func (c Calendar) change(id int) *Change {
+	for i := range c.changes {
+		if c.changes[i].ID == id {
+			return &c.changes[i]
+		}
+	}
+	return nil
+}
+
+// lastChange returns the most recent change of the log, nil if it is empty
+func (c Calendar) lastChange() *Change {
+	if len(c.changes) == 0 {
+		return nil
+	}
+	return &c.changes[len(c.changes)-1]
+}
+
+// Reversible tells if the change can still be undone
+func (c Calendar) Reversible(change Change) bool {
+	if change.Undone {
+		return false
+	}
+
+	var event = c.dates[change.Date]
+	switch change.Kind {
+	case CHANGE_EDITED:
+		return true
+	case CHANGE_ADDED:
+		return event != nil
+	case CHANGE_REMOVED:
+		date, err := change.Date.Parse()
+		return err == nil && event == nil && change.Event != nil && !date.IsBefore(Now())
+	case CHANGE_JOINED:
+		return event != nil && event.hasJoined(change.UserID)
+	case CHANGE_LEFT:
+		return event != nil && !event.hasJoined(change.UserID) && c.HasFreeSeats(change.Date)
+	}
+	return false
+}
+
+// Affected returns the attendees that are warned when the change is undone
+func (c Calendar) Affected(change Change) []int64 {
+	switch change.Kind {
+	case CHANGE_EDITED:
+		if change.Field == "name" || change.Field == "description" {
+			return c.AllCurrentAttendee()
+		}
+	case CHANGE_ADDED:
+		return c.CurrentAttendee(change.Date)
+	case CHANGE_REMOVED:
+		if change.Event != nil {
+			return change.Event.attendee
+		}
+	case CHANGE_JOINED, CHANGE_LEFT:
+		return []int64{change.UserID}
+	}
+	return nil
+}
+
+// UndoChange reverts a change of the log of the calendar on behalf of the given
+// user, recording it as a new change, and warns the affected attendees
+func UndoChange(calendar *Calendar, id int, by int64) (warned int, err error) {
+	var change = calendar.change(id)
+	if change == nil {
+		return 0, INVALID_CHANGE
+	}
+	if !calendar.Reversible(*change) {
+		return 0, NOT_REVERSIBLE
+	}
+
+	var undone, affected = *change, calendar.Affected(*change)
+	switch undone.Kind {
+	case CHANGE_EDITED:
+		_, warned, err = EditCalendar(calendar, undone.Field, undone.Previous, by)
+		affected = nil
+	case CHANGE_ADDED:
+		undone.Days = calendar.Days(undone.Date)
+		RemoveFromCalendar(calendar, undone.Date)
+		warnAttendees(calendar, affected, "undo.cancelled", undone)
+	case CHANGE_REMOVED:
+		if !RestoreDate(calendar, undone.Date, *undone.Event) {
+			return 0, NOT_REVERSIBLE
+		}
+		warnAttendees(calendar, affected, "undo.restored", undone)
+	case CHANGE_JOINED:
+		if _, err = LeaveEvent(undone.UserID, calendar.invitation, string(undone.Date), by); err == nil {
+			warnAttendees(calendar, affected, "undo.left", undone)
+		}
+	case CHANGE_LEFT:
+		if _, err = JoinEvent(echotron.User{ID: undone.UserID}, calendar.invitation, string(undone.Date), true, by); err == nil {
+			warnAttendees(calendar, affected, "undo.joined", undone)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// the log might have been reallocated recording the undo
+	if change = calendar.change(id); change != nil {
+		change.Undone = true
+	}
+	return warned + len(affected), nil
+}
+
+// warnAttendees tells the users that the organizer changed again an event they joined
+func warnAttendees(calendar *Calendar, users []int64, key string, change Change) {
+	for _, userID := range users {
+		lang := LanguageOf(userID)
+		telegram.Send(userID, genDefaultMessage(icon("❕"), lang.T(key, html.EscapeString(calendar.name), describeDate(change.Date, change.Days, lang))))
+	}
+}
+
+// organizerOf returns the ID of the user that owns the calendar
+func organizerOf(calendar *Calendar) int64 {
+	if ownerID := retreiveOwner(calendar.invitation); ownerID != nil {
+		return *ownerID
+	}
+	return 0
+}
+
+// Describe returns a human readable version of the change, seen by the organizer
+func (change Change) Describe(lang Language, organizerID int64) string {
+	var (
+		actor = lang.T("change.user", change.Actor)
+		user  = lang.T("change.user", change.UserID)
+		date  = describeDate(change.Date, change.Days, lang)
+		text  string
+	)
+	if change.Actor == organizerID {
+		actor = lang.T("change.you")
+	}
+
+	switch change.Kind {
+	case CHANGE_EDITED:
+		text = lang.T("change.edited", actor, change.Field, html.EscapeString(change.Previous), html.EscapeString(change.Value))
+	case CHANGE_ADDED:
+		text = lang.T("change.added", actor, date)
+	case CHANGE_REMOVED:
+		var attendees int
+		if change.Event != nil {
+			attendees = change.Event.countAttendee()
+		}
+		text = lang.Plural("change.removed", attendees, actor, date)
+	case CHANGE_JOINED:
+		if text = lang.T("change.joined", user, date); change.Actor != change.UserID {
+			text = lang.T("change.joined_by", actor, user, date)
+		}
+	case CHANGE_LEFT:
+		if text = lang.T("change.left", user, date); change.Actor != change.UserID {
+			text = lang.T("change.left_by", actor, user, date)
+		}
+	}
+
+	if change.Undone {
+		text += lang.T("change.undone")
+	}
+	return text
+}
+
+// buildChangesMessage shows a page of the log of the calendar, from the most recent
+// change, with the buttons to undo the ones that are still reversible
+func buildChangesMessage(lang Language, c Calendar, organizerID int64, page int) (text string, kbd [][]tgui.InlineButton) {
+	var pages = (len(c.changes)-1)/DATE_LIST_SIZE + 1
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	text = lang.Plural("change.title", len(c.changes), html.EscapeString(c.name))
+	if len(c.changes) == 0 {
+		return text + lang.T("change.empty"), tgui.Wrap(tgui.Wrap(closeButton(lang)))
+	}
+
+	var undo []tgui.InlineButton
+	for i := len(c.changes) - 1 - page*DATE_LIST_SIZE; i >= 0 && i >= len(c.changes)-(page+1)*DATE_LIST_SIZE; i-- {
+		var change = c.changes[i]
+		text += lang.T("change.entry", change.ID, lang.Format(change.At.In(config.location), BEAUTIFIED_FORMAT), change.Describe(lang, organizerID))
+		if c.Reversible(change) {
+			undo = append(undo, tgui.InlineCaller(fmt.Sprint("↩️ ", change.ID), "/changes", "undo", fmt.Sprint(change.ID), fmt.Sprint(page)))
+		}
+	}
+	kbd = tgui.Arrange(4, undo...)
+
+	if pages > 1 {
+		var nav []tgui.InlineButton
+		if page > 0 {
+			nav = append(nav, tgui.InlineCaller("⏮", "/changes", fmt.Sprint(page-1)))
+		}
+		nav = append(nav, alertCaller(icon("📄"), fmt.Sprint(page+1, "/", pages), lang.T("list.page", page+1, pages)))
+		if page < pages-1 {
+			nav = append(nav, tgui.InlineCaller("⏭", "/changes", fmt.Sprint(page+1)))
+		}
+		kbd = append(kbd, nav)
+	}
+	return text, append(kbd, tgui.Wrap(closeButton(lang)))
+}
+
+// buildUndoMessage asks to confirm the undo of a change, telling who will be warned
+func buildUndoMessage(lang Language, c Calendar, organizerID int64, change Change, page string) (text string, kbd [][]tgui.InlineButton) {
+	text = lang.T("change.confirm", change.Describe(lang, organizerID))
+	if affected := len(c.Affected(change)); affected > 0 {
+		text += lang.Plural("change.will_warn", affected)
+	}
+
+	return text, [][]tgui.InlineButton{
+		{tgui.InlineCaller(CONFIRM.Text(lang.T("btn.confirm")), "/changes", "confirm", fmt.Sprint(change.ID), page)},
+		{backButton(lang, "/changes", page), closeButton(lang)},
+	}
+}
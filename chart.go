@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+/* --- CHARTS --- */
+
+const (
+	CHART_WIDTH  = 800
+	CHART_HEIGHT = 480
+	CHART_MARGIN = 24 // space around the plot, also used for the labels
+	CHART_SCALE  = 2  // size in pixels of each dot of the font
+)
+
+// Colors used by the charts, the index is the one in the palette
+const (
+	chartBackground uint8 = iota
+	chartBar
+	chartText
+	chartGrid
+)
+
+var chartPalette = color.Palette{
+	color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+	color.RGBA{0x2A, 0x9D, 0xF4, 0xFF},
+	color.RGBA{0x33, 0x33, 0x33, 0xFF},
+	color.RGBA{0xDD, 0xDD, 0xDD, 0xFF},
+}
+
+// BarChartPNG renders a bar chart of the values as a PNG image, each bar is
+// described by the label at the same index. Only digits, latin letters and a
+// few symbols are written, labels that do not fit are skipped
+func BarChartPNG(labels []string, values []int) ([]byte, error) {
+	if len(labels) != len(values) || len(values) == 0 {
+		return nil, fmt.Errorf("invalid chart: %d labels for %d values", len(labels), len(values))
+	}
+
+	var (
+		img     = image.NewPaletted(image.Rect(0, 0, CHART_WIDTH, CHART_HEIGHT), chartPalette)
+		line    = (FONT_HEIGHT + 2) * CHART_SCALE
+		top     = CHART_MARGIN + line // room for the values over the highest bar
+		bottom  = CHART_HEIGHT - CHART_MARGIN - line
+		step    = (CHART_WIDTH - 2*CHART_MARGIN) / len(values)
+		highest = 1
+		widest  int
+	)
+	for i := range values {
+		highest = max(highest, values[i])
+		widest = max(widest, textWidth(labels[i])+FONT_WIDTH*CHART_SCALE)
+	}
+	var every = (widest + step - 1) / step // draw a label each few bars when they are too wide
+
+	for i := 0; i <= 4; i++ {
+		fillRect(img, CHART_MARGIN, bottom-(bottom-top)*i/4, CHART_WIDTH-CHART_MARGIN, bottom-(bottom-top)*i/4+1, chartGrid)
+	}
+	for i, value := range values {
+		var (
+			left   = CHART_MARGIN + i*step
+			center = left + step/2
+			height = (bottom - top) * value / highest
+		)
+		fillRect(img, left+step/6, bottom-height, left+step-step/6, bottom, chartBar)
+
+		if value > 0 {
+			if text := fmt.Sprint(value); textWidth(text) <= step {
+				drawText(img, center-textWidth(text)/2, bottom-height-line, text)
+			}
+		}
+		if i%every == 0 {
+			drawText(img, center-textWidth(labels[i])/2, bottom+CHART_SCALE*2, labels[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fillRect(img *image.Paletted, x0, y0, x1, y1 int, colorIndex uint8) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			img.SetColorIndex(x, y, colorIndex)
+		}
+	}
+}
+
+// textWidth returns the width in pixels of the text written by drawText
+func textWidth(text string) int {
+	var n = len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*(FONT_WIDTH+1) - 1) * CHART_SCALE
+}
+
+// drawText writes the text in uppercase with the top left corner in the given point
+func drawText(img *image.Paletted, x, y int, text string) {
+	for _, char := range strings.ToUpper(text) {
+		for row, bits := range font[char] {
+			for col := 0; col < FONT_WIDTH; col++ {
+				if bits&(1<<(FONT_WIDTH-1-col)) != 0 {
+					fillRect(img, x+col*CHART_SCALE, y+row*CHART_SCALE, x+(col+1)*CHART_SCALE, y+(row+1)*CHART_SCALE, chartText)
+				}
+			}
+		}
+		x += (FONT_WIDTH + 1) * CHART_SCALE
+	}
+}
+
+/* --- FONT --- */
+
+const (
+	FONT_WIDTH  = 5
+	FONT_HEIGHT = 7
+)
+
+// font is a tiny bitmap font, each glyph is a row of bits per line
+var font = map[rune][FONT_HEIGHT]uint8{
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11110, 0b00001, 0b00001, 0b01110, 0b00001, 0b00001, 0b11110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'.': {0b00000, 0b00000, 0b00000, 0b00000, 0b00000, 0b01100, 0b01100},
+	':': {0b00000, 0b01100, 0b01100, 0b00000, 0b01100, 0b01100, 0b00000},
+	'-': {0b00000, 0b00000, 0b00000, 0b11111, 0b00000, 0b00000, 0b00000},
+	'/': {0b00000, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b00000},
+	'%': {0b11000, 0b11001, 0b00010, 0b00100, 0b01000, 0b10011, 0b00011},
+}
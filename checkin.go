@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/tgui"
+)
+
+/* --- CHECK-IN --- */
+
+const (
+	CHECKIN_WINDOW      = 3 * time.Hour   // how long a check-in stays open, also after the start of the event
+	CHECKIN_ADVANCE     = time.Hour       // how long before the event a check-in can be opened
+	CHECKIN_ROTATION    = 2 * time.Minute // how often the code changes, the previous one is still accepted
+	CHECKIN_CODE_DIGITS = 6
+	CHECKIN_LINK_PREFIX = "c"
+)
+
+// checkIn is an open window in which the attendees can be marked present using its rotating code
+type checkIn struct {
+	secret []byte
+	until  time.Time
+}
+
+// checkIns are the open check-ins by event, using the payload of the event links as key
+var checkIns = map[string]*checkIn{}
+
+// checkInKey is the key of the check-in of an event, empty if the event can't be linked
+func checkInKey(invitation string, date FormattedDate) string {
+	var payload, _ = EncodeEventLink(invitation, date)
+	return strings.TrimPrefix(payload, EVENT_LINK_PREFIX)
+}
+
+// CanCheckIn tells if a check-in can be opened for the given date, that is
+// from a bit before the event until the end of the window after its start,
+// or until its end for the all-day events
+func CanCheckIn(c Calendar, date FormattedDate) bool {
+	var start, end, err = c.Interval(date)
+	if err != nil || c.event(date) == nil {
+		return false
+	}
+	if c.Days(date) == 0 {
+		end = start.Add(CHECKIN_WINDOW)
+	}
+	var now = clock.Now()
+	return !now.Before(start.Add(-CHECKIN_ADVANCE)) && now.Before(end)
+}
+
+// OpenCheckIn opens the check-in of an event, or returns the one already open
+func OpenCheckIn(calendar *Calendar, date FormattedDate) (*checkIn, error) {
+	if open := CheckInOf(*calendar, date); open != nil {
+		return open, nil
+	}
+	var key = checkInKey(calendar.invitation, date)
+	if key == "" || !CanCheckIn(*calendar, date) {
+		return nil, CHECKIN_CLOSED
+	}
+
+	var open = &checkIn{secret: make([]byte, 16), until: clock.Now().Add(CHECKIN_WINDOW)}
+	if _, err := rand.Read(open.secret); err != nil {
+		return nil, err
+	}
+	checkIns[key] = open
+	return open, nil
+}
+
+// CloseCheckIn closes the check-in of an event, if open
+func CloseCheckIn(calendar *Calendar, date FormattedDate) {
+	delete(checkIns, checkInKey(calendar.invitation, date))
+}
+
+// CheckInOf returns the open check-in of an event, nil if there is none
+func CheckInOf(c Calendar, date FormattedDate) *checkIn {
+	var key = checkInKey(c.invitation, date)
+	if open := checkIns[key]; open != nil && clock.Now().Before(open.until) && c.event(date) != nil {
+		return open
+	}
+	delete(checkIns, key)
+	return nil
+}
+
+// Code returns the code accepted at the given time
+func (ci checkIn) Code(at time.Time) string {
+	return ci.code(at.Unix() / int64(CHECKIN_ROTATION/time.Second))
+}
+
+// Rotation returns when the code shown at the given time will be replaced
+func (ci checkIn) Rotation(at time.Time) time.Time {
+	var period = int64(CHECKIN_ROTATION / time.Second)
+	return time.Unix((at.Unix()/period+1)*period, 0)
+}
+
+// accepts tells if the code is the current one or the previous one
+func (ci checkIn) accepts(code string, at time.Time) bool {
+	var period = at.Unix() / int64(CHECKIN_ROTATION/time.Second)
+	return hmac.Equal([]byte(code), []byte(ci.code(period))) || hmac.Equal([]byte(code), []byte(ci.code(period-1)))
+}
+
+func (ci checkIn) code(period int64) string {
+	var (
+		mac            = hmac.New(sha256.New, ci.secret)
+		counter        = make([]byte, 8)
+		modulo  uint32 = 1
+	)
+	binary.BigEndian.PutUint64(counter, uint64(period))
+	mac.Write(counter)
+	for i := 0; i < CHECKIN_CODE_DIGITS; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", CHECKIN_CODE_DIGITS, binary.BigEndian.Uint32(mac.Sum(nil))%modulo)
+}
+
+// CheckInEvent marks as present the user that sent the code of an open check-in,
+// when key is not empty only the check-in of that event is considered
+func CheckInEvent(userID int64, key, code string) (calendar *Calendar, date FormattedDate, err error) {
+	var now = clock.Now()
+	code = strings.TrimSpace(code)
+	for openKey, open := range checkIns {
+		if (key != "" && openKey != key) || !now.Before(open.until) || !open.accepts(code, now) {
+			continue
+		}
+
+		invitation, openDate, _ := DecodeEventLink(EVENT_LINK_PREFIX + openKey)
+		if calendar = retreiveCalendar(invitation); calendar == nil || calendar.event(openDate) == nil {
+			continue
+		}
+		if err = calendar.event(openDate).attend(userID); err != nil {
+			return calendar, openDate, err
+		}
+		Emit(calendar, HOOK_ATTENDED, map[string]interface{}{
+			"date":     hookDate(openDate),
+			"user_id":  userID,
+			"joined":   calendar.event(openDate).hasJoined(userID),
+			"attended": len(calendar.event(openDate).attended),
+		})
+		return calendar, openDate, nil
+	}
+	return nil, "", INVALID_CODE
+}
+
+// GetCheckInLink returns the link that checks in the event with the given code
+func GetCheckInLink(botUsername string, c Calendar, date FormattedDate, code string) string {
+	var payload = CHECKIN_LINK_PREFIX + checkInKey(c.invitation, date) + "-" + code
+	if botUsername == "" {
+		return "/start " + payload
+	}
+	return "t.me/" + botUsername + "?start=" + payload
+}
+
+// DecodeCheckInLink reads the key of the event and the code from the payload of a check-in link
+func DecodeCheckInLink(payload string) (key, code string, ok bool) {
+	if !strings.HasPrefix(payload, CHECKIN_LINK_PREFIX) {
+		return
+	}
+	var cut = strings.LastIndexByte(payload, '-')
+	if cut < 0 {
+		return
+	}
+	key, code = strings.TrimPrefix(payload[:cut], CHECKIN_LINK_PREFIX), payload[cut+1:]
+	if _, _, ok = DecodeEventLink(EVENT_LINK_PREFIX + key); !ok || len(code) != CHECKIN_CODE_DIGITS {
+		return "", "", false
+	}
+	return key, code, true
+}
+
+// buildCheckInMessage shows to the organizer the current code of an open check-in
+func buildCheckInMessage(lang Language, c Calendar, date FormattedDate, open checkIn) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		now   = clock.Now()
+		event = c.event(date)
+	)
+	text = lang.T("checkin.show",
+		html.EscapeString(c.name), c.Describe(date, lang),
+		open.Code(now),
+		lang.Format(open.Rotation(now).In(config.location), "15:04:05"),
+		len(event.attended), event.countAttendee(),
+		lang.Format(open.until.In(config.location), "15:04"),
+	)
+	kbd = [][]tgui.InlineButton{
+		{
+			tgui.InlineCaller(REFRESH.Text(lang.T("btn.refresh")), "/checkin", "show", string(date)),
+			tgui.InlineCaller(lang.T("btn.qr_code"), "/checkin", "qr", string(date)),
+		},
+		{
+			tgui.InlineCaller("📋 "+lang.T("btn.roster"), "/checkin", "roster", string(date)),
+			tgui.InlineCaller("🔒 "+lang.T("btn.close_checkin"), "/checkin", "close", string(date)),
+		},
+		tgui.Wrap(closeButton(lang)),
+	}
+	return
+}
+
+// buildCheckInList proposes to the organizer the events whose check-in can be opened
+func buildCheckInList(lang Language, c Calendar) (text string, kbd [][]tgui.InlineButton) {
+	var dates []FormattedDate
+	for _, date := range append(c.SortedArchive(), c.SortedDates()...) {
+		if CanCheckIn(c, date) || CheckInOf(c, date) != nil {
+			dates = append(dates, date)
+		}
+	}
+
+	text = lang.T("checkin.help")
+	if len(dates) == 0 {
+		text += lang.T("checkin.none")
+	} else {
+		text += lang.T("checkin.pick")
+	}
+	for _, date := range dates {
+		var caption = c.Describe(date, lang)
+		if CheckInOf(c, date) != nil {
+			caption = DONE.Text(caption)
+		}
+		kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(caption, "/checkin", "show", string(date))))
+	}
+	return text, append(kbd, tgui.Wrap(closeButton(lang)))
+}
+
+// buildCheckInDone confirms to the attendee the check-in
+func buildCheckInDone(lang Language, c Calendar, date FormattedDate) message.Text {
+	return genDefaultMessage(DONE, lang.T("checkin.done", html.EscapeString(c.name), c.Describe(date, lang)), tgui.Wrap(closeButton(lang)))
+}
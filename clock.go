@@ -0,0 +1,34 @@
+package main
+
+import "time"
+
+/* --- CLOCK --- */
+
+// Clock is where the bot reads the current time and waits for it to pass,
+// so that it can be replaced with a fake one
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after the duration d has passed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled with Clock.AfterFunc
+type Timer interface {
+	// Stop prevents the call, returns false if it already happened or was stopped
+	Stop() bool
+}
+
+// clock is the Clock in use, the system one by default
+var clock Clock = systemClock{}
+
+// systemClock uses the real time of the system
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
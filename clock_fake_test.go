@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/* --- FAKE CLOCK --- */
+
+// FakeClock is a Clock where time passes only when Advance is called,
+// the scheduled calls are made synchronously in chronological order
+type FakeClock struct {
+	sync.Mutex
+	now     time.Time
+	pending []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	f     func()
+}
+
+// NewFakeClock creates a FakeClock set at the given time and starts using it instead of the system one
+func NewFakeClock(now time.Time) *FakeClock {
+	fake := &FakeClock{now: now}
+	clock = fake
+	return fake
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.Lock()
+	defer c.Unlock()
+
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.pending = append(c.pending, timer)
+	return timer
+}
+
+// Advance moves the clock forward making all the calls scheduled in the meantime,
+// the ones scheduled by them are made too if they fall within the same period
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	var until = c.now.Add(d)
+	for {
+		sort.SliceStable(c.pending, func(i, j int) bool {
+			return c.pending[i].at.Before(c.pending[j].at)
+		})
+		if len(c.pending) == 0 || c.pending[0].at.After(until) {
+			break
+		}
+
+		var next = c.pending[0]
+		c.pending = c.pending[1:]
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		c.Unlock()
+		next.f()
+		c.Lock()
+	}
+	c.now = until
+	c.Unlock()
+}
+
+// Pending returns the number of the calls scheduled that have not been made yet
+func (c *FakeClock) Pending() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.pending)
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	for i, pending := range t.clock.pending {
+		if pending == t {
+			t.clock.pending = append(t.clock.pending[:i:i], t.clock.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* --- CONFIGURATION --- */
+
+// Configuration contains all the settings of the bot, loaded at startup
+type Configuration struct {
+	Token            string     `json:"token"`             // Telegram Bot API token
+	Storage          string     `json:"storage"`           // path of the file where data is saved, empty means no persistence
+	Reminders        []Duration `json:"reminders"`         // how long before an event the attendee are reminded
+	CleanupInterval  Duration   `json:"cleanup_interval"`  // how often the unused calendars are deleted
+	UnusedAfter      Duration   `json:"unused_after"`      // time after wich a calendar can be consider unused
+	TimeZone         string     `json:"time_zone"`         // IANA name of the time zone used for the dates
+	Admins           []int64    `json:"admins"`            // user ID of the bot's administrators
+	RateLimit        RateLimit  `json:"rate_limit"`        // max number of updates handled per user
+	AlertCacheTime   uint16     `json:"alert_cache_time"`  // seconds a toast alert might be cached client-side (max 3600)
+	HTTPAddress      string     `json:"http_address"`      // address where the HTTP API listens (ex: ":8080"), empty means disabled
+	PublicURL        string     `json:"public_url"`        // URL where the HTTP server can be reached from outside, used for the links
+	FeedbackAfter    Duration   `json:"feedback_after"`    // how long after an event the attendees are asked to rate it, 0 means never
+	ArchiveRetention Duration   `json:"archive_retention"` // how long the events that occurred are kept in the history, 0 means forever
+
+	location *time.Location
+}
+
+// RateLimit is the max number of requests that a single user can do in a period of time
+type RateLimit struct {
+	Requests int      `json:"requests"` // 0 means no limit
+	Period   Duration `json:"period"`
+}
+
+// Prefix of the environment variables that can override the configuration file
+const ENV_PREFIX = "CALENDAGGERBILL_"
+
+// config is the configuration currently in use
+var config = DefaultConfiguration()
+
+// DefaultConfiguration returns the configuration used when nothing else is specified
+func DefaultConfiguration() Configuration {
+	return Configuration{
+		Reminders:       []Duration{Duration(time.Hour * 24 * 7), Duration(time.Hour * 24)},
+		CleanupInterval: Duration(DEFAULT_UNUSED_TIME),
+		UnusedAfter:     Duration(DEFAULT_UNUSED_TIME),
+		TimeZone:        "Local",
+		RateLimit:       RateLimit{Requests: 30, Period: Duration(time.Minute)},
+		AlertCacheTime:  MAX_CACHE_TIME,
+		location:        time.Local,
+	}
+}
+
+// LoadConfiguration reads the command line arguments to build the configuration. It accepts:
+//   - "--config <path>" to read a JSON configuration file
+//   - "--readfrom <path>" to read the token from a file (legacy)
+//   - the token itself as only argument (legacy)
+//
+// Environment variables are applied on top of that and the result is validated
+func LoadConfiguration(args []string) (c Configuration, err error) {
+	var (
+		flags    = flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+		path     = flags.String("config", "", "path of the JSON configuration file")
+		readfrom = flags.String("readfrom", "", "path of the file containing the bot token")
+	)
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	c = DefaultConfiguration()
+	if *path != "" {
+		if err = c.readFile(*path); err != nil {
+			return
+		}
+	}
+
+	switch {
+	case flags.NArg() > 1:
+		return c, errors.New("too many arguments")
+	case flags.NArg() == 1:
+		c.Token = flags.Arg(0)
+	case *readfrom != "":
+		var content []byte
+		if content, err = os.ReadFile(*readfrom); err != nil {
+			return c, fmt.Errorf("unable to read token: %w", err)
+		}
+		c.Token = strings.TrimSpace(string(content))
+	}
+
+	if err = c.applyEnv(os.LookupEnv); err == nil {
+		err = c.Validate()
+	}
+	return
+}
+
+// readFile loads the values of a JSON configuration file over the current ones
+func (c *Configuration) readFile(path string) error {
+	var content, err = os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read configuration file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid configuration file %q: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides the configuration using the environment variables found with lookup
+func (c *Configuration) applyEnv(lookup func(string) (string, bool)) error {
+	var errs ConfigError
+
+	env := func(name string, apply func(value string) error) {
+		if value, ok := lookup(ENV_PREFIX + name); ok {
+			if err := apply(strings.TrimSpace(value)); err != nil {
+				errs = append(errs, fmt.Sprint(ENV_PREFIX, name, ": ", err))
+			}
+		}
+	}
+
+	env("TOKEN", func(value string) error {
+		c.Token = value
+		return nil
+	})
+	env("STORAGE", func(value string) error {
+		c.Storage = value
+		return nil
+	})
+	env("REMINDERS", func(value string) (err error) {
+		c.Reminders = nil
+		for _, raw := range splitList(value) {
+			var d Duration
+			if d, err = ParseDuration(raw); err != nil {
+				return
+			}
+			c.Reminders = append(c.Reminders, d)
+		}
+		return
+	})
+	env("CLEANUP_INTERVAL", func(value string) (err error) {
+		c.CleanupInterval, err = ParseDuration(value)
+		return
+	})
+	env("UNUSED_AFTER", func(value string) (err error) {
+		c.UnusedAfter, err = ParseDuration(value)
+		return
+	})
+	env("TIME_ZONE", func(value string) error {
+		c.TimeZone = value
+		return nil
+	})
+	env("ADMINS", func(value string) error {
+		c.Admins = nil
+		for _, raw := range splitList(value) {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid user ID %q", raw)
+			}
+			c.Admins = append(c.Admins, id)
+		}
+		return nil
+	})
+	env("RATE_LIMIT_REQUESTS", func(value string) (err error) {
+		c.RateLimit.Requests, err = strconv.Atoi(value)
+		return
+	})
+	env("RATE_LIMIT_PERIOD", func(value string) (err error) {
+		c.RateLimit.Period, err = ParseDuration(value)
+		return
+	})
+	env("ALERT_CACHE_TIME", func(value string) error {
+		seconds, err := strconv.ParseUint(value, 10, 16)
+		c.AlertCacheTime = uint16(seconds)
+		return err
+	})
+
+	env("HTTP_ADDRESS", func(value string) error {
+		c.HTTPAddress = value
+		return nil
+	})
+	env("PUBLIC_URL", func(value string) error {
+		c.PublicURL = value
+		return nil
+	})
+	env("FEEDBACK_AFTER", func(value string) (err error) {
+		c.FeedbackAfter, err = ParseDuration(value)
+		return
+	})
+	env("ARCHIVE_RETENTION", func(value string) (err error) {
+		c.ArchiveRetention, err = ParseDuration(value)
+		return
+	})
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate checks that all the values of the configuration are usable and
+// initialize the derived ones, all the problems found are reported together
+func (c *Configuration) Validate() error {
+	var errs ConfigError
+
+	if c.Token == "" {
+		errs = append(errs, "missing token, use the configuration file, the "+ENV_PREFIX+"TOKEN variable or pass it as argument")
+	} else if !regexp.MustCompile(`^\d+:[\w\-]+$`).MatchString(c.Token) {
+		errs = append(errs, "wrong format for the token")
+	}
+
+	if c.Storage != "" {
+		if info, err := os.Stat(filepath.Dir(c.Storage)); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Sprintf("storage: the folder of %q does not exist", c.Storage))
+		}
+	}
+
+	for _, reminder := range c.Reminders {
+		if reminder <= 0 {
+			errs = append(errs, fmt.Sprintf("reminders: %s is not a positive duration", reminder))
+		}
+	}
+	if c.CleanupInterval <= 0 {
+		errs = append(errs, "cleanup_interval: must be a positive duration")
+	}
+	if c.UnusedAfter <= 0 {
+		errs = append(errs, "unused_after: must be a positive duration")
+	}
+
+	if location, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Sprintf("time_zone: unknown time zone %q", c.TimeZone))
+	} else {
+		c.location = location
+	}
+
+	if c.RateLimit.Requests < 0 {
+		errs = append(errs, "rate_limit.requests: cannot be negative")
+	} else if c.RateLimit.Requests > 0 && c.RateLimit.Period <= 0 {
+		errs = append(errs, "rate_limit.period: must be a positive duration when requests are limited")
+	}
+	if c.AlertCacheTime > MAX_CACHE_TIME {
+		errs = append(errs, fmt.Sprint("alert_cache_time: cannot be more than ", MAX_CACHE_TIME, " seconds"))
+	}
+
+	if c.HTTPAddress != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPAddress); err != nil {
+			errs = append(errs, fmt.Sprintf("http_address: invalid address %q", c.HTTPAddress))
+		}
+	}
+	if c.PublicURL != "" {
+		if parsed, err := url.Parse(c.PublicURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Sprintf("public_url: invalid URL %q", c.PublicURL))
+		}
+	}
+
+	if c.FeedbackAfter < 0 {
+		errs = append(errs, "feedback_after: cannot be negative")
+	}
+	if c.ArchiveRetention < 0 {
+		errs = append(errs, "archive_retention: cannot be negative")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// IsAdmin tells if the given user is one of the administrators of the bot
+func (c Configuration) IsAdmin(userID int64) bool {
+	for _, adminID := range c.Admins {
+		if adminID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigError is the list of all the problems found in a configuration
+type ConfigError []string
+
+func (e ConfigError) Error() string {
+	return "invalid configuration:\n - " + strings.Join(e, "\n - ")
+}
+
+/* --- DURATION --- */
+
+// Duration is a time.Duration that in JSON is written like "36h" or "7d"
+type Duration time.Duration
+
+// ParseDuration is like time.ParseDuration but also accepts days, ex: "7d" or "1d12h"
+func ParseDuration(source string) (Duration, error) {
+	var days int
+	if ind := strings.IndexRune(source, 'd'); ind > 0 {
+		var err error
+		if days, err = strconv.Atoi(source[:ind]); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", source)
+		}
+		if source = source[ind+1:]; source == "" {
+			source = "0s"
+		}
+	}
+
+	d, err := time.ParseDuration(source)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", source)
+	}
+	return Duration(d + time.Duration(days)*24*time.Hour), nil
+}
+
+// String writes the duration omitting the zero units at the end, ex: "1h30m" instead of "1h30m0s"
+func (d Duration) String() string {
+	var text = time.Duration(d).String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	var raw string
+	if err = json.Unmarshal(data, &raw); err == nil {
+		*d, err = ParseDuration(raw)
+	}
+	return
+}
+
+/* --- UTILITIES --- */
+
+// splitList splits a comma separated list ignoring empty values
+func splitList(source string) (list []string) {
+	for _, value := range strings.Split(source, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return
+}
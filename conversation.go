@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/robot"
+	"github.com/DazFather/parrbot/tgui"
+)
+
+/* --- CONVERSATIONS --- */
+
+// How long a conversation waits for the answer to its question
+const CONVERSATION_TIMEOUT = 15 * time.Minute
+
+// Conversation is a multi-step exchange with a chat, in which each step asks a
+// question and awaits as answer the next text message or the tap of a button
+type Conversation struct {
+	Flow    string            `json:"flow"`
+	Steps   []string          `json:"steps"`          // visited, the last one is the current
+	Data    map[string]string `json:"data,omitempty"` // collected in the previous steps
+	Expires time.Time         `json:"expires"`
+}
+
+// Step returns the current step of the conversation
+func (conv Conversation) Step() string {
+	return conv.Steps[len(conv.Steps)-1]
+}
+
+// Flow describes a kind of conversation
+type Flow struct {
+	// Ask returns the question of the current step, kbd can propose some answers using answerButton
+	Ask func(chatID int64, lang Language, conv Conversation) (text string, kbd [][]tgui.InlineButton)
+	// Answer handles the answer to the current step, returning the next one or,
+	// if empty, ends the conversation sending the reply. An error asks again the step
+	Answer func(chatID int64, lang Language, conv *Conversation, answer string) (next string, reply message.Any, err error)
+	// Cancelled, if set, returns the text shown when the conversation is cancelled
+	Cancelled func(lang Language, conv Conversation) string
+	// CancelLabel, if set, is the key of the label of the button that cancels the conversation
+	CancelLabel string
+	// Confirm, if set, ends the conversation when its confirmButton is tapped,
+	// returning the message that replaces the question
+	Confirm func(chatID int64, lang Language, conv Conversation) (text string, kbd [][]tgui.InlineButton)
+}
+
+var (
+	// flows are the kinds of conversations, by name
+	flows = map[string]Flow{
+		COMMENT_FLOW: commentFlow,
+		EDIT_FLOW:    editFlow,
+	}
+	// conversations are the ones in progress, by chat
+	conversations = map[int64]*Conversation{}
+	// expirations are the timers ending the conversations in progress
+	expirations = map[int64]Timer{}
+)
+
+// Converse starts a conversation of the given flow with the chat, replacing the
+// one in progress, and returns the question of the last of the given steps
+func Converse(chatID int64, lang Language, flow string, data map[string]string, steps ...string) (text string, kbd [][]tgui.InlineButton) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	conversations[chatID] = &Conversation{Flow: flow, Steps: steps, Data: data}
+	return ask(chatID, lang)
+}
+
+// ConversationOf returns the conversation in progress with the chat, nil if there is none
+func ConversationOf(chatID int64) *Conversation {
+	var conv = conversations[chatID]
+	if conv == nil || !clock.Now().Before(conv.Expires) {
+		EndConversation(chatID)
+		return nil
+	}
+	return conv
+}
+
+// EndConversation ends the conversation in progress with the chat, if any
+func EndConversation(chatID int64) {
+	if timer := expirations[chatID]; timer != nil {
+		timer.Stop()
+	}
+	delete(expirations, chatID)
+	delete(conversations, chatID)
+}
+
+// ask returns the question of the current step of the conversation with the
+// chat, with the buttons to go back and cancel, and restarts its timeout
+func ask(chatID int64, lang Language) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		conv = conversations[chatID]
+		flow = flows[conv.Flow]
+		row  []tgui.InlineButton
+	)
+	conv.Expires = clock.Now().Add(CONVERSATION_TIMEOUT)
+	expire(chatID, conv)
+
+	text, kbd = flow.Ask(chatID, lang, *conv)
+	if len(conv.Steps) > 1 {
+		row = append(row, backButton(lang, "/conversation", "back"))
+	}
+	var cancel = CANCEL.Text(lang.T("btn.cancel"))
+	if flow.CancelLabel != "" {
+		cancel = lang.T(flow.CancelLabel)
+	}
+	return QUESTION.Text(text), append(kbd, append(row, tgui.InlineCaller(cancel, "/conversation", "cancel")))
+}
+
+// expire sets the end of the conversation with the chat when its timeout passes
+func expire(chatID int64, conv *Conversation) {
+	if timer := expirations[chatID]; timer != nil {
+		timer.Stop()
+	}
+	expirations[chatID] = clock.AfterFunc(conv.Expires.Sub(clock.Now()), func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
+		if conversations[chatID] != conv || clock.Now().Before(conv.Expires) {
+			return
+		}
+		EndConversation(chatID)
+		var lang = LanguageOf(chatID)
+		telegram.Send(chatID, genDefaultMessage(icon("⌛"), lang.T("conversation.expired"), tgui.Wrap(closeButton(lang))))
+	})
+}
+
+// answerButton is a button that answers the current step of a conversation with the given value
+func answerButton(label, value string) tgui.InlineButton {
+	return tgui.InlineCaller(label, "/conversation", "answer", value)
+}
+
+// confirmButton is a button that ends the conversation confirming the collected data
+func confirmButton(lang Language) tgui.InlineButton {
+	return tgui.InlineCaller(CONFIRM.Text(lang.T("btn.confirm")), "/conversation", "confirm")
+}
+
+// Answer handles the answer to the current step of the conversation with the
+// chat, returning the next question or the reply that ends the conversation
+func Answer(chatID int64, lang Language, answer string) message.Any {
+	var conv = ConversationOf(chatID)
+	if conv == nil {
+		return nil
+	}
+
+	next, reply, err := flows[conv.Flow].Answer(chatID, lang, conv, strings.TrimSpace(answer))
+	if err != nil {
+		text, kbd := ask(chatID, lang)
+		return genDefaultMessage(BLOCK, err.Error()+"\n\n"+text, kbd...)
+	}
+	if next == "" {
+		EndConversation(chatID)
+		return reply
+	}
+
+	conv.Steps = append(conv.Steps, next)
+	text, kbd := ask(chatID, lang)
+	return genDefaultMessage(icon(""), text, kbd...)
+}
+
+// answerHandler receives the text messages answering the conversations
+var answerHandler = robot.Command{
+	ReplyAt: message.MESSAGE,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		if strings.TrimSpace(update.Message.Text) == "" {
+			return nil
+		}
+		return Answer(bot.ChatID, extractLanguage(bot, update), update.Message.Text)
+	},
+}
+
+// conversationHandler answers, goes back or cancels the conversations using their buttons
+var conversationHandler = robot.Command{
+	Trigger: "/conversation",
+	ReplyAt: message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+			conv    = ConversationOf(bot.ChatID)
+		)
+		if conv == nil {
+			Collapse(update.CallbackQuery, BLOCK, lang.T("conversation.expired"))
+			return nil
+		}
+
+		switch flow := flows[conv.Flow]; append(payload, "")[0] {
+		case "confirm":
+			if flow.Confirm == nil {
+				Collapse(update.CallbackQuery, BLOCK, lang.T("error.no_payload"))
+				break
+			}
+			EndConversation(bot.ChatID)
+			text, kbd := flow.Confirm(bot.ChatID, lang, *conv)
+			showMessage(update, text, genDefaultEditOpt(kbd...))
+		case "answer":
+			telegram.Answer(update.CallbackQuery, nil)
+			telegram.Delete(update.CallbackQuery.Message)
+			return Answer(bot.ChatID, lang, strings.Join(payload[1:], " "))
+		case "back":
+			if len(conv.Steps) > 1 {
+				conv.Steps = conv.Steps[:len(conv.Steps)-1]
+			}
+			text, kbd := ask(bot.ChatID, lang)
+			showMessage(update, text, genDefaultEditOpt(kbd...))
+		default:
+			var text = DONE.Text(lang.T("alert.cancelled"))
+			if cancelled := flow.Cancelled; cancelled != nil {
+				text = cancelled(lang, *conv)
+			}
+			EndConversation(bot.ChatID)
+			showMessage(update, text, genDefaultEditOpt(tgui.Wrap(closeButton(lang))))
+		}
+		return nil
+	},
+}
+
+// cancelHandler cancels the conversation in progress using a command
+var cancelHandler = robot.Command{
+	Description: "Cancel the current operation",
+	Trigger:     "/cancel",
+	ReplyAt:     message.MESSAGE,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var lang = extractLanguage(bot, update)
+		telegram.Delete(update.Message)
+		if ConversationOf(bot.ChatID) == nil {
+			return buildErrorMessage(lang, lang.T("conversation.none"))
+		}
+		EndConversation(bot.ChatID)
+		return genDefaultMessage(DONE, lang.T("alert.cancelled"), tgui.Wrap(closeButton(lang)))
+	},
+}
+
+/* --- FLOWS --- */
+
+const (
+	COMMENT_FLOW = "comment" // comment to the rating of an event
+	EDIT_FLOW    = "edit"    // change of a field of the calendar
+)
+
+// commentFlow awaits the comment to the rating of an event, in the data are the
+// invitation of the calendar, the date of the event and the rating
+var commentFlow = Flow{
+	Ask: func(chatID int64, lang Language, conv Conversation) (string, [][]tgui.InlineButton) {
+		var rating, _ = strconv.Atoi(conv.Data["rating"])
+		return lang.T("feedback.rated", strings.Repeat("⭐", rating)), nil
+	},
+	Answer: func(chatID int64, lang Language, conv *Conversation, answer string) (string, message.Any, error) {
+		if _, err := CommentEvent(chatID, conv.Data["invitation"], FormattedDate(conv.Data["date"]), answer); err != nil {
+			return "", buildErrorMessage(lang, lang.Error(err)), nil
+		}
+		return "", genDefaultMessage(DONE, lang.T("feedback.thanks"), tgui.Wrap(closeButton(lang))), nil
+	},
+	Cancelled: func(lang Language, conv Conversation) string {
+		return DONE.Text(lang.T("feedback.thanks"))
+	},
+	CancelLabel: "btn.skip_comment",
+}
+
+// Fields of the calendar that can be edited
+var EDITABLE_FIELDS = []string{"name", "description", "notification", "capacity", "duration"}
+
+// editFlow asks the field of the calendar to edit and its new value, then the
+// confirmation of the change, in the data are the chosen field and value
+var editFlow = Flow{
+	Ask: func(chatID int64, lang Language, conv Conversation) (text string, kbd [][]tgui.InlineButton) {
+		var (
+			calendar = CalendarOf(chatID)
+			field    = conv.Data["field"]
+			current  string
+		)
+		switch conv.Step() {
+		case "field":
+			var buttons = make([]tgui.InlineButton, len(EDITABLE_FIELDS))
+			for i, field := range EDITABLE_FIELDS {
+				buttons[i] = answerButton(lang.T("field."+field), field)
+			}
+			return lang.T("edit.ask_field"), tgui.Arrange(3, buttons...)
+		case "confirm":
+			if calendar != nil {
+				text, _ = buildEditMessage(lang, *calendar, field, conv.Data["value"])
+			}
+			return text, tgui.Wrap(tgui.Wrap(confirmButton(lang)))
+		}
+
+		if calendar != nil {
+			current, _ = FieldOf(*calendar, field)
+		}
+		if field == "notification" {
+			kbd = tgui.Wrap([]tgui.InlineButton{answerButton(lang.T("btn.on"), "on"), answerButton(lang.T("btn.off"), "off")})
+		}
+		return lang.T("edit.ask_"+field, html.EscapeString(current)), kbd
+	},
+	Answer: func(chatID int64, lang Language, conv *Conversation, answer string) (string, message.Any, error) {
+		var calendar = CalendarOf(chatID)
+		if calendar == nil {
+			return "", buildErrorMessage(lang, lang.T("error.no_calendar")), nil
+		}
+
+		if conv.Step() == "field" {
+			var field = strings.ToLower(answer)
+			if _, err := FieldOf(*calendar, field); err != nil {
+				return "", nil, errors.New(lang.T("edit.invalid_field", html.EscapeString(answer)))
+			}
+			conv.Data["field"] = field
+			return "value", nil, nil
+		}
+
+		if _, err := buildEditMessage(lang, *calendar, conv.Data["field"], answer); err != nil {
+			return "", nil, err
+		}
+		// a value sent while confirming replaces the previous one
+		if conv.Step() == "confirm" {
+			conv.Steps = conv.Steps[:len(conv.Steps)-1]
+		}
+		conv.Data["value"] = answer
+		return "confirm", nil, nil
+	},
+	Confirm: func(chatID int64, lang Language, conv Conversation) (string, [][]tgui.InlineButton) {
+		var (
+			calendar     = CalendarOf(chatID)
+			field, value = conv.Data["field"], conv.Data["value"]
+		)
+		if calendar == nil {
+			return BLOCK.Text(lang.T("set.no_calendar")), tgui.Wrap(tgui.Wrap(closeButton(lang)))
+		}
+
+		previous, warned, err := EditCalendar(calendar, field, value, chatID)
+		if err != nil {
+			return BLOCK.Text(lang.Error(err)), tgui.Wrap(tgui.Wrap(closeButton(lang)))
+		}
+
+		text := lang.T("set.done", field, html.EscapeString(value))
+		if warned > 0 {
+			text += lang.Plural("set.warned", warned)
+		}
+
+		var row = []tgui.InlineButton{tgui.InlineCaller("📜 "+lang.T("btn.changes"), "/changes"), closeButton(lang)}
+		if change := calendar.lastChange(); change != nil && change.Kind == CHANGE_EDITED && change.Field == field {
+			row = append([]tgui.InlineButton{
+				tgui.InlineCaller(lang.T("btn.turn_back", field, previous), "/changes", "undo", strconv.Itoa(change.ID), "0"),
+			}, row...)
+		}
+		return DONE.Text(text), tgui.Wrap(row)
+	},
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"errors"
-	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/NicoNex/echotron/v3"
@@ -14,6 +14,78 @@ const DEFAULT_UNUSED_TIME = time.Hour * 24 * 30 * 6
 
 var organizers = map[int64]*Calendar{}
 
+// Preferences are the settings chosen by a user
+type Preferences struct {
+	lang        Language
+	langChosen  bool          // if the language was explicitly chosen, otherwise it's the Telegram one
+	weekStart   *time.Weekday // first day of the week, nil means the one of the language
+	weekNumbers bool          // show the ISO week numbers in the calendar grid
+	feed        string        // token of the iCalendar feed of the joined events, empty if never requested
+}
+
+var preferences = map[int64]*Preferences{}
+
+// PreferencesOf grabs the preferences of a certain user, creating them if missing
+func PreferencesOf(userID int64) *Preferences {
+	var pref = preferences[userID]
+	if pref == nil {
+		pref = &Preferences{lang: DEFAULT_LANGUAGE}
+		preferences[userID] = pref
+	}
+	return pref
+}
+
+// LanguageOf grabs the language of a certain user
+func LanguageOf(userID int64) Language {
+	if pref, ok := preferences[userID]; ok {
+		return pref.lang
+	}
+	return DEFAULT_LANGUAGE
+}
+
+// DetectLanguage returns the language chosen by the user or the one of its Telegram client
+func DetectLanguage(user echotron.User) Language {
+	var pref = PreferencesOf(user.ID)
+	if !pref.langChosen {
+		pref.lang = ParseLanguage(user.LanguageCode)
+	}
+	return pref.lang
+}
+
+// SetLanguage saves the language explicitly chosen by a user
+func SetLanguage(userID int64, lang Language) {
+	var pref = PreferencesOf(userID)
+	pref.lang, pref.langChosen = lang, true
+}
+
+// WeekStartOf grabs the first day of the week for a certain user
+func WeekStartOf(userID int64) time.Weekday {
+	if pref, ok := preferences[userID]; ok && pref.weekStart != nil {
+		return *pref.weekStart
+	}
+	return LanguageOf(userID).WeekStart()
+}
+
+// SetWeekStart saves the first day of the week chosen by a user
+func SetWeekStart(userID int64, day time.Weekday) {
+	PreferencesOf(userID).weekStart = &day
+}
+
+// ShowWeekNumbers tells if a certain user wants to see the ISO week numbers
+func ShowWeekNumbers(userID int64) bool {
+	if pref, ok := preferences[userID]; ok {
+		return pref.weekNumbers
+	}
+	return false
+}
+
+// ToggleWeekNumbers shows or hides the ISO week numbers for a certain user
+func ToggleWeekNumbers(userID int64) bool {
+	var pref = PreferencesOf(userID)
+	pref.weekNumbers = !pref.weekNumbers
+	return pref.weekNumbers
+}
+
 // Grab the calendar od a certain user
 func CalendarOf(userID int64) *Calendar {
 	return organizers[userID]
@@ -22,9 +94,28 @@ func CalendarOf(userID int64) *Calendar {
 // UnusedCalendarsRemover returns a function that delete all unused calendars saved
 func UnusedCalendarsRemover(considerUnusedAfter time.Duration) (remover func()) {
 	return func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
 		for userID, calendar := range organizers {
 			if calendar.IsUnused(considerUnusedAfter) {
 				delete(organizers, userID)
+				RefreshCards(calendar)
+			}
+		}
+	}
+}
+
+// ExpiredArchiveRemover returns a function that delete from the archives all
+// the events occurred before the given retention
+func ExpiredArchiveRemover(retention time.Duration) (remover func()) {
+	return func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
+		for _, calendar := range organizers {
+			for _, date := range calendar.pruneArchive(clock.Now().Add(-retention)) {
+				unschedule(calendar, date)
 			}
 		}
 	}
@@ -32,11 +123,19 @@ func UnusedCalendarsRemover(considerUnusedAfter time.Duration) (remover func())
 
 // AddToCalendar adds dates to a calendar, if it does not exists yet, it creates a new one
 func AddToCalendar(user echotron.User, dates ...Date) *Calendar {
-	var calendar *Calendar = organizers[user.ID]
+	calendar, _ := AddDatesToCalendar(user, dates...)
+	return calendar
+}
+
+// AddDatesToCalendar is like AddToCalendar but also returns the dates that
+// have been added, the others were already in the calendar
+func AddDatesToCalendar(user echotron.User, dates ...Date) (calendar *Calendar, added []FormattedDate) {
+	calendar = organizers[user.ID]
 	if calendar == nil {
+		lang := LanguageOf(user.ID)
 		calendar = NewCalendar(
-			user.FirstName+" calendar",
-			user.FirstName+" personal event",
+			lang.T("calendar.default_name", user.FirstName),
+			lang.T("calendar.default_description", user.FirstName),
 			strconv.Itoa(int(user.ID)),
 		)
 		calendar.dates = make(map[FormattedDate]*Event, len(dates))
@@ -44,74 +143,448 @@ func AddToCalendar(user echotron.User, dates ...Date) *Calendar {
 	}
 
 	for _, date := range dates {
-		timestamp := date.Formatted()
-		if !calendar.addDate(timestamp) {
-			continue
+		if calendar.addDate(date.Formatted()) {
+			schedule(calendar, date)
+			Emit(calendar, HOOK_DATE_ADDED, map[string]interface{}{"date": hookDate(date.Formatted())})
+			calendar.record(Change{Kind: CHANGE_ADDED, Actor: user.ID, Date: date.Formatted()})
+			added = append(added, date.Formatted())
 		}
+	}
+	RefreshCards(calendar)
 
-		date.Skip(0, 0, -7).WhenOccurrs(
-			remind(calendar, timestamp, fmt.Sprint("Don't forget the ", calendar.name, ", is cooming soon: ", date)),
-		)
+	return calendar, added
+}
 
-		date.Skip(0, 0, -1).WhenOccurrs(
-			remind(calendar, timestamp, fmt.Sprint("a Tomorrow ", date, ", there will be ", calendar.name, " waiting for you!")),
-		)
+// AddSpanToCalendar adds an all-day event covering the days from first to last,
+// added is false when the calendar already has an event at the midnight of the first day
+func AddSpanToCalendar(user echotron.User, first, last Date) (calendar *Calendar, added bool) {
+	var (
+		start = first.DayStart()
+		days  = int(last.DayStart().Sub(start.Time).Hours()/24+0.5) + 1
+	)
+	calendar = AddToCalendar(user)
+	if days < 1 || !calendar.addSpan(start.Formatted(), days) {
+		return calendar, false
+	}
 
-		date.WhenOccurrs(func() {
-			calendar.removeDate(timestamp)
+	schedule(calendar, start)
+	Emit(calendar, HOOK_DATE_ADDED, map[string]interface{}{"date": hookDate(start.Formatted()), "days": days})
+	calendar.record(Change{Kind: CHANGE_ADDED, Actor: user.ID, Date: start.Formatted(), Days: days})
+	RefreshCards(calendar)
+	return calendar, true
+}
+
+// RestoreDate adds back to the calendar an event that has been removed, with
+// its attendees, returning false if the calendar already has one in that date
+func RestoreDate(calendar *Calendar, date FormattedDate, event Event) bool {
+	var parsed, err = date.Parse()
+	if err != nil || calendar.dates[date] != nil {
+		return false
+	}
+
+	event.attendee = append([]int64(nil), event.attendee...)
+	calendar.dates[date] = &event
+	calendar.lastTimeUsed = Now()
+	schedule(calendar, parsed)
+	Emit(calendar, HOOK_DATE_ADDED, map[string]interface{}{"date": hookDate(date), "days": event.days})
+	calendar.record(Change{Kind: CHANGE_ADDED, Actor: organizerOf(calendar), Date: date, Days: event.days})
+	RefreshCards(calendar)
+	return true
+}
+
+// VisitCalendar counts the opening of an invitation link, ignoring the organizer
+func VisitCalendar(calendar *Calendar, userID int64) {
+	if ownerID := retreiveOwner(calendar.invitation); ownerID == nil || *ownerID != userID {
+		calendar.visit(userID)
+	}
+}
+
+// RemoveFromCalendar removes a date from a calendar with its reminders
+func RemoveFromCalendar(calendar *Calendar, date FormattedDate) *Event {
+	unschedule(calendar, date)
+	deleted := calendar.removeDate(date)
+	if deleted != nil {
+		Emit(calendar, HOOK_DATE_REMOVED, map[string]interface{}{
+			"date":      hookDate(date),
+			"attendees": deleted.attendee,
 		})
+		calendar.record(Change{Kind: CHANGE_REMOVED, Actor: organizerOf(calendar), Date: date, Days: deleted.days, Event: deleted})
+		RefreshCards(calendar)
 	}
+	return deleted
+}
+
+// ArchiveDate moves a date that occurred to the archive of the calendar,
+// keeping its attendees, and schedules the request of their feedback
+func ArchiveDate(calendar *Calendar, date FormattedDate) *Event {
+	unschedule(calendar, date)
+	archived := calendar.archiveDate(date)
+	if archived != nil {
+		Emit(calendar, HOOK_DATE_ARCHIVED, map[string]interface{}{
+			"date":      hookDate(date),
+			"attendees": archived.attendee,
+		})
+		RefreshCards(calendar)
+		scheduleFeedback(calendar, date)
+	}
+	return archived
+}
 
+// DeleteCalendar deletes the calendar of a certain user with all its dates
+func DeleteCalendar(userID int64) *Calendar {
+	var calendar = organizers[userID]
+	if calendar != nil {
+		for date := range calendar.dates {
+			RemoveFromCalendar(calendar, date)
+		}
+		for date := range calendar.archive {
+			unschedule(calendar, date)
+		}
+		delete(organizers, userID)
+		RefreshCards(calendar)
+	}
 	return calendar
 }
 
-// remind creates a reminder function
-func remind(calendar *Calendar, date FormattedDate, text string) (reminder func()) {
+// timers are the reminders and removals scheduled for each date of the calendars
+var timers = map[*Calendar]map[FormattedDate][]Timer{}
+
+// schedule sets the configured reminders of a date of the calendar that has
+// not passed yet and its archival when it will occur
+func schedule(calendar *Calendar, date Date) {
+	var (
+		timestamp = date.Formatted()
+		now       = Now()
+		occurred  = date
+		scheduled []Timer
+	)
+	if at, err := calendar.Occurred(timestamp); err == nil {
+		occurred = Parse(at)
+	}
+
+	for _, before := range config.Reminders {
+		if at := Parse(date.Add(-time.Duration(before))); at.IsAfter(now) {
+			scheduled = append(scheduled, at.WhenOccurrs(remind(calendar, timestamp, time.Duration(before))))
+		}
+	}
+
+	scheduled = append(scheduled, occurred.WhenOccurrs(func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+		ArchiveDate(calendar, timestamp)
+	}))
+
+	if timers[calendar] == nil {
+		timers[calendar] = map[FormattedDate][]Timer{}
+	}
+	timers[calendar][timestamp] = scheduled
+}
+
+// unschedule stops the reminders and the removal of a date of the calendar
+func unschedule(calendar *Calendar, date FormattedDate) {
+	for _, timer := range timers[calendar][date] {
+		timer.Stop()
+	}
+	delete(timers[calendar], date)
+	if len(timers[calendar]) == 0 {
+		delete(timers, calendar)
+	}
+}
+
+// scheduleFeedback sets the request of feedback to the attendees of an archived
+// event after the configured time, if they have not been asked yet
+func scheduleFeedback(calendar *Calendar, date FormattedDate) {
+	var event = calendar.archive[date]
+	if config.FeedbackAfter <= 0 || event == nil || event.asked || event.countAttendee() == 0 {
+		return
+	}
+	occurred, err := calendar.Occurred(date)
+	if err != nil {
+		return
+	}
+
+	at := Parse(occurred.Add(time.Duration(config.FeedbackAfter)))
+	if timers[calendar] == nil {
+		timers[calendar] = map[FormattedDate][]Timer{}
+	}
+	timers[calendar][date] = []Timer{at.WhenOccurrs(func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+		askFeedback(calendar, date)
+	})}
+}
+
+// askFeedback sends to all the attendees of an archived event the request to rate it
+func askFeedback(calendar *Calendar, date FormattedDate) {
+	unschedule(calendar, date)
+	var event = calendar.archive[date]
+	if event == nil || event.asked {
+		return
+	}
+
+	event.asked = true
+	for _, userID := range event.attendee {
+		telegram.Send(userID, buildFeedbackRequest(LanguageOf(userID), *calendar, date))
+	}
+}
+
+// RateEvent saves the rating given by an attendee to an archived event
+func RateEvent(userID int64, invitation string, date FormattedDate, rating int) (calendar *Calendar, err error) {
+	if calendar = retreiveCalendar(invitation); calendar == nil {
+		return nil, INVALID_CALENDAR
+	}
+	var event = calendar.archive[date]
+	if event == nil {
+		return calendar, INVALID_EVENT
+	}
+	if err = event.rate(userID, rating); err == nil {
+		Emit(calendar, HOOK_RATED, map[string]interface{}{
+			"date":    hookDate(date),
+			"user_id": userID,
+			"rating":  rating,
+		})
+	}
+	return
+}
+
+// CommentEvent adds a comment to the rating given by an attendee to an archived event
+func CommentEvent(userID int64, invitation string, date FormattedDate, text string) (calendar *Calendar, err error) {
+	if calendar = retreiveCalendar(invitation); calendar == nil {
+		return nil, INVALID_CALENDAR
+	}
+	var event = calendar.archive[date]
+	if event == nil {
+		return calendar, INVALID_EVENT
+	}
+	if err = event.comment(userID, strings.TrimSpace(text)); err == nil {
+		Emit(calendar, HOOK_RATED, map[string]interface{}{
+			"date":    hookDate(date),
+			"user_id": userID,
+			"rating":  event.feedback[userID].Rating,
+			"comment": event.feedback[userID].Comment,
+		})
+	}
+	return
+}
+
+// remind creates a reminder function, the text is translated for each attendee
+func remind(calendar *Calendar, date FormattedDate, before time.Duration) (reminder func()) {
 	return func() {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
 		if calendar == nil || !calendar.notification {
 			return
 		}
 
-		for userID := range calendar.CurrentAttendee(date) {
-			genDefaultMessage(NOTIF_ON, text).Send(int64(userID))
+		for _, userID := range calendar.CurrentAttendee(date) {
+			var (
+				lang = LanguageOf(userID)
+				text string
+			)
+			switch before {
+			case time.Hour * 24 * 7:
+				text = lang.T("reminder.week", calendar.name, calendar.Describe(date, lang))
+			case time.Hour * 24:
+				text = lang.T("reminder.tomorrow", calendar.name, calendar.Describe(date, lang))
+			default:
+				text = lang.T("reminder.generic", calendar.name, lang.Duration(before), calendar.Describe(date, lang))
+			}
+			telegram.Send(userID, genDefaultMessage(NOTIF_ON, text))
+		}
+
+		if attendee := calendar.CurrentAttendee(date); len(attendee) > 0 {
+			Emit(calendar, HOOK_REMINDED, map[string]interface{}{
+				"date":      hookDate(date),
+				"before":    Duration(before),
+				"attendees": attendee,
+			})
 		}
 	}
 }
 
-// JoinEvent makes a user join an event having an invitation and a date
-func JoinEvent(user echotron.User, invitation, rawDate string) (calendar *Calendar, err error) {
+// JoinEvent makes a user join an event having an invitation and a date, on behalf
+// of the given user, the organizer is notified only when they join by themselves
+func JoinEvent(user echotron.User, invitation, rawDate string, force bool, by int64) (calendar *Calendar, err error) {
 	var (
 		timestamp FormattedDate
 		ownerID   *int64 = retreiveOwner(invitation)
 	)
 
 	if ownerID == nil {
-		return nil, errors.New("Invalid invitation")
+		return nil, INVALID_INVITATION
 	}
 
-	if date, err := ParseDate(rawDate); err == nil {
-		calendar = organizers[*ownerID]
-		timestamp = date.Formatted()
-		err = calendar.joinDate(timestamp, user.ID)
-	}
+	date, err := ParseDate(rawDate)
 	if err != nil {
+		return nil, INVALID_EVENT
+	}
+	if calendar = organizers[*ownerID]; calendar == nil {
+		return nil, INVALID_CALENDAR
+	}
+	timestamp = date.Formatted()
+	if event := calendar.event(timestamp); !force && event != nil && !event.hasJoined(user.ID) && len(Conflicts(user.ID, calendar, timestamp)) > 0 {
+		return calendar, OVERLAPPING
+	}
+	if err = calendar.joinDate(timestamp, user.ID); err != nil {
 		return
 	}
+	Emit(calendar, HOOK_JOINED, map[string]interface{}{
+		"date":      hookDate(timestamp),
+		"user_id":   user.ID,
+		"attendees": calendar.CountAttendee(timestamp),
+	})
+	calendar.record(Change{Kind: CHANGE_JOINED, Actor: by, Date: timestamp, Days: calendar.Days(timestamp), UserID: user.ID})
+	RefreshCards(calendar)
 
-	if calendar.notification {
+	if calendar.notification && by == user.ID {
 		name := user.Username
 		if name == "" {
 			name = user.FirstName
 		} else {
 			name = "@" + name
 		}
-		count := "+ 1"
-		if tot := calendar.CountAttendee(timestamp) - 1; tot > 0 {
-			count = fmt.Sprint(tot, " ", count)
+		lang := LanguageOf(*ownerID)
+		sendNotification(*ownerID, lang.Plural("notification.joined", calendar.CountAttendee(timestamp), name, calendar.Describe(timestamp, lang)))
+	}
+
+	return
+}
+
+// Conflict is an event joined by a user overlapping another one
+type Conflict struct {
+	Calendar *Calendar
+	Date     FormattedDate
+}
+
+// Conflicts returns the upcoming events joined by the user that overlap the given one
+func Conflicts(userID int64, calendar *Calendar, date FormattedDate) (conflicts []Conflict) {
+	for _, other := range organizers {
+		for _, otherDate := range other.SortedDates() {
+			if other == calendar && otherDate == date {
+				continue
+			}
+			if other.dates[otherDate].hasJoined(userID) && calendar.Overlaps(date, *other, otherDate) {
+				conflicts = append(conflicts, Conflict{other, otherDate})
+			}
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, _ := conflicts[i].Date.Parse()
+		b, _ := conflicts[j].Date.Parse()
+		return a.IsBefore(b)
+	})
+	return
+}
+
+// LeaveEvent makes a user leave an event previously joined, on behalf of the given user
+func LeaveEvent(userID int64, invitation, rawDate string, by int64) (calendar *Calendar, err error) {
+	var ownerID *int64 = retreiveOwner(invitation)
+	if ownerID == nil {
+		return nil, INVALID_INVITATION
+	}
+
+	date, err := ParseDate(rawDate)
+	if err != nil {
+		return nil, INVALID_EVENT
+	}
+	calendar = organizers[*ownerID]
+	if err = calendar.leaveDate(date.Formatted(), userID); err == nil {
+		Emit(calendar, HOOK_LEFT, map[string]interface{}{
+			"date":      hookDate(date.Formatted()),
+			"user_id":   userID,
+			"attendees": calendar.CountAttendee(date.Formatted()),
+		})
+		calendar.record(Change{Kind: CHANGE_LEFT, Actor: by, Date: date.Formatted(), Days: calendar.Days(date.Formatted()), UserID: userID})
+		RefreshCards(calendar)
+	}
+	return
+}
+
+// ValidateEdit checks if a field of the calendar can be changed to the given value
+func ValidateEdit(field, value string) error {
+	switch field {
+	case "notification":
+		if ParseToggler(value) == nil {
+			return INVALID_VALUE
+		}
+	case "name", "description":
+		if strings.TrimSpace(value) == "" {
+			return INVALID_VALUE
 		}
-		sendNotification(*ownerID, fmt.Sprint("<b>", count, "</b>: ", name, " joined your event in date: ", timestamp.Beautify()))
+	case "capacity":
+		if capacity, err := strconv.Atoi(value); err != nil || capacity < 0 {
+			return INVALID_VALUE
+		}
+	case "duration":
+		if duration, err := ParseDuration(value); err != nil || duration <= 0 {
+			return INVALID_VALUE
+		}
+	default:
+		return INVALID_FIELD
+	}
+	return nil
+}
+
+// FieldOf returns the current value of a field of the calendar that can be edited
+func FieldOf(c Calendar, field string) (string, error) {
+	switch field {
+	case "notification":
+		return c.notification.String(), nil
+	case "name":
+		return c.name, nil
+	case "description":
+		return c.description, nil
+	case "capacity":
+		return strconv.Itoa(c.capacity), nil
+	case "duration":
+		return Duration(c.Duration()).String(), nil
+	}
+	return "", INVALID_FIELD
+}
+
+// EditCalendar changes a field of the calendar (name, description, notification or capacity)
+// on behalf of the given user, warning the attendee when they might not recognize it anymore
+func EditCalendar(calendar *Calendar, field, value string, by int64) (previous string, warned int, err error) {
+	if err = ValidateEdit(field, value); err != nil {
+		return
 	}
 
+	var needWarning bool
+	previous, _ = FieldOf(*calendar, field)
+	switch field {
+	case "notification":
+		calendar.notification = *ParseToggler(value)
+	case "name":
+		calendar.name = strings.TrimSpace(value)
+		needWarning = true
+	case "description":
+		calendar.description = strings.TrimSpace(value)
+		needWarning = true
+	case "capacity":
+		calendar.capacity, _ = strconv.Atoi(value)
+	case "duration":
+		duration, _ := ParseDuration(value)
+		calendar.duration = time.Duration(duration)
+	}
+	calendar.lastTimeUsed = Now()
+	if current, _ := FieldOf(*calendar, field); current != previous {
+		calendar.record(Change{Kind: CHANGE_EDITED, Actor: by, Field: field, Previous: previous, Value: current})
+	}
+	Emit(calendar, HOOK_EDITED, map[string]interface{}{
+		"field":    field,
+		"previous": previous,
+		"value":    value,
+	})
+	RefreshCards(calendar)
+
+	if needWarning && previous != value {
+		for _, userID := range calendar.AllCurrentAttendee() {
+			userLang := LanguageOf(userID)
+			telegram.Send(userID, genDefaultMessage(icon("❕"), userLang.T("set.warning", field, previous, value)))
+			warned++
+		}
+	}
 	return
 }
 
@@ -123,6 +596,62 @@ func GetShareLink(botUsername string, c Calendar) string {
 	return "t.me/" + botUsername + "?start=" + c.invitation
 }
 
+// Prefix of the start payloads pointing to a single event
+const EVENT_LINK_PREFIX = "e"
+
+// GetEventLink grabs the shareable link that opens directly a date of a calendar
+func GetEventLink(botUsername string, c Calendar, date FormattedDate) string {
+	var payload, ok = EncodeEventLink(c.invitation, date)
+	if !ok {
+		return GetShareLink(botUsername, c)
+	}
+	if botUsername == "" {
+		return "/start " + payload
+	}
+	return "t.me/" + botUsername + "?start=" + payload
+}
+
+// EncodeEventLink writes invitation and date in a start payload that uses only
+// the allowed characters and fits the limit of 64, ok is false if any is invalid
+func EncodeEventLink(invitation string, date FormattedDate) (payload string, ok bool) {
+	userID, err := strconv.ParseInt(invitation, 10, 64)
+	if err != nil || userID <= 0 {
+		return "", false
+	}
+	wall, err := time.Parse(DATETIME_FROMAT, string(date)) // read as UTC to not depend on the time zone
+	if err != nil {
+		return "", false
+	}
+	return EVENT_LINK_PREFIX + strconv.FormatInt(userID, 36) + "-" + strconv.FormatInt(wall.Unix()/60, 36), true
+}
+
+// DecodeEventLink reads invitation and date from a start payload made by EncodeEventLink
+func DecodeEventLink(payload string) (invitation string, date FormattedDate, ok bool) {
+	rawID, rawDate, found := strings.Cut(strings.TrimPrefix(payload, EVENT_LINK_PREFIX), "-")
+	if !strings.HasPrefix(payload, EVENT_LINK_PREFIX) || !found {
+		return
+	}
+
+	userID, err := strconv.ParseInt(rawID, 36, 64)
+	if err != nil || userID <= 0 {
+		return
+	}
+	minutes, err := strconv.ParseInt(rawDate, 36, 64)
+	if err != nil || minutes < 0 {
+		return
+	}
+	return strconv.FormatInt(userID, 10), Format(time.Unix(minutes*60, 0).UTC()), true
+}
+
+// publicLink turns a link made by GetShareLink or GetEventLink into a full URL,
+// it's empty when the bot has no username
+func publicLink(link string) string {
+	if strings.HasPrefix(link, "t.me/") {
+		return "https://" + link
+	}
+	return ""
+}
+
 func retreiveOwner(invitation string) *int64 {
 	var rawID, err = strconv.Atoi(invitation)
 	if err != nil {
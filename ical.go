@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/* --- ICALENDAR FEEDS --- */
+
+const (
+	ICAL_DATE_FORMAT = "20060102T150405Z"
+	ICAL_DAY_FORMAT  = "20060102" // of the all-day events
+	ICAL_REFRESH     = "PT1H"     // how often the clients are suggested to update the feeds
+)
+
+// FeedsEnabled tells if the feeds can be reached by the calendar clients
+func FeedsEnabled() bool {
+	return config.HTTPAddress != "" && config.PublicURL != ""
+}
+
+// CalendarFeed returns the URL of the feed of all the events of a calendar
+func CalendarFeed(calendar *Calendar) string {
+	if calendar.feed == "" {
+		calendar.feed = randomHex(16)
+	}
+	return feedURL("calendar", calendar.feed)
+}
+
+// AttendeeFeed returns the URL of the feed of all the events joined by a user
+func AttendeeFeed(userID int64) string {
+	var pref = PreferencesOf(userID)
+	if pref.feed == "" {
+		pref.feed = randomHex(16)
+	}
+	return feedURL("user", pref.feed)
+}
+
+func feedURL(kind, token string) string {
+	return strings.TrimRight(config.PublicURL, "/") + "/feed/" + kind + "/" + token + ".ics"
+}
+
+// serveFeed handles /feed/calendar/{token}.ics and /feed/user/{token}.ics
+func serveFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		path  = strings.Split(strings.TrimPrefix(r.URL.Path, "/feed/"), "/")
+		token string
+		feed  string
+	)
+	if len(path) != 2 || !strings.HasSuffix(path[1], ".ics") {
+		http.NotFound(w, r)
+		return
+	}
+	if token = strings.TrimSuffix(path[1], ".ics"); token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	dataLock.Lock()
+	switch path[0] {
+	case "calendar":
+		for _, calendar := range organizers {
+			if calendar.feed == token {
+				feed = buildCalendarFeed(*calendar)
+				break
+			}
+		}
+	case "user":
+		for userID, pref := range preferences {
+			if pref.feed == token {
+				feed = buildAttendeeFeed(userID)
+				break
+			}
+		}
+	}
+	dataLock.Unlock()
+
+	if feed == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(feed))
+}
+
+// buildCalendarFeed writes all the events of a calendar in the iCalendar format
+func buildCalendarFeed(c Calendar) string {
+	var events []string
+	for _, date := range c.SortedDates() {
+		if event := buildFeedEvent(c, date, ""); event != "" {
+			events = append(events, event)
+		}
+	}
+	return buildFeed(c.name, c.description, events)
+}
+
+// buildAttendeeFeed writes all the events joined by a user in the iCalendar format
+func buildAttendeeFeed(userID int64) string {
+	var (
+		lang   = LanguageOf(userID)
+		events []string
+	)
+	for _, calendar := range organizers {
+		for _, date := range calendar.SortedDates() {
+			if event := calendar.dates[date]; event != nil && event.hasJoined(userID) {
+				events = append(events, buildFeedEvent(*calendar, date, ""))
+			}
+		}
+	}
+	return buildFeed(lang.T("feed.attendee_name"), lang.T("feed.attendee_description"), events)
+}
+
+// buildFeed wraps the events into a calendar
+func buildFeed(name, description string, events []string) string {
+	var (
+		feed     strings.Builder
+		timeZone = config.location.String()
+	)
+	if timeZone == "Local" {
+		timeZone = ""
+	}
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//DazFather//CalenDaggerbill//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escapeICal(name),
+		"X-WR-CALDESC:" + escapeICal(description),
+		"X-WR-TIMEZONE:" + timeZone,
+		"REFRESH-INTERVAL;VALUE=DURATION:" + ICAL_REFRESH,
+		"X-PUBLISHED-TTL:" + ICAL_REFRESH,
+	} {
+		if strings.IndexByte(line, ':') < len(line)-1 { // skip the properties without value
+			feed.WriteString(foldICal(line))
+		}
+	}
+	for _, event := range events {
+		feed.WriteString(event)
+	}
+	feed.WriteString("END:VCALENDAR\r\n")
+	return feed.String()
+}
+
+// buildFeedEvent writes an event of the calendar in the iCalendar format,
+// when uid is empty the default one is used
+func buildFeedEvent(c Calendar, date FormattedDate, uid string) string {
+	var start, err = date.Parse()
+	if err != nil {
+		return ""
+	}
+	if uid == "" {
+		uid = eventUID(c, start)
+	}
+
+	var (
+		event     strings.Builder
+		lang      = DEFAULT_LANGUAGE
+		link      = publicLink(GetEventLink(telegram.Username(), c, date))
+		startLine = "DTSTART:" + start.UTC().Format(ICAL_DATE_FORMAT)
+		endLine   = "DURATION:" + fmt.Sprintf("PT%dM", int(c.Duration().Minutes()))
+	)
+	if days := c.Days(date); days > 0 {
+		startLine = "DTSTART;VALUE=DATE:" + start.Format(ICAL_DAY_FORMAT)
+		endLine = "DTEND;VALUE=DATE:" + start.AddDate(0, 0, days).Format(ICAL_DAY_FORMAT)
+	}
+	if ownerID := retreiveOwner(c.invitation); ownerID != nil {
+		lang = LanguageOf(*ownerID)
+	}
+
+	for _, line := range []string{
+		"BEGIN:VEVENT",
+		"UID:" + escapeICal(uid),
+		"DTSTAMP:" + clock.Now().UTC().Format(ICAL_DATE_FORMAT),
+		startLine,
+		endLine,
+		"SUMMARY:" + escapeICal(c.name),
+		"DESCRIPTION:" + escapeICal(c.description+"\n\n"+lang.Plural("day.attendee", c.CountAttendee(date))),
+		"URL:" + link,
+		"END:VEVENT",
+	} {
+		if strings.IndexByte(line, ':') < len(line)-1 { // skip the properties without value
+			event.WriteString(foldICal(line))
+		}
+	}
+	return event.String()
+}
+
+// eventUID is the unique identifier of an event across all calendars
+func eventUID(c Calendar, start Date) string {
+	return fmt.Sprint(c.invitation, "-", start.UTC().Format("20060102T1504"), "@calendaggerbill")
+}
+
+// escapeICal escapes the special characters of an iCalendar text value
+func escapeICal(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// foldICal ends the line splitting it in lines of max 75 bytes without breaking characters
+func foldICal(line string) string {
+	var (
+		folded strings.Builder
+		size   int
+	)
+	for _, char := range line {
+		if n := len(string(char)); size+n > 75 {
+			folded.WriteString("\r\n ")
+			size = 1
+		}
+		folded.WriteRune(char)
+		size += len(string(char))
+	}
+	folded.WriteString("\r\n")
+	return folded.String()
+}
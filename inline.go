@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- INLINE MODE --- */
+
+// Max number of results proposed for an inline query (Telegram allows 50)
+const INLINE_RESULTS = 20
+
+// Kind of the cards, also used in the callback data of their join buttons
+const (
+	CALENDAR_CARD = "calendar"
+	EVENT_CARD    = "event"
+)
+
+// inlineCard is a message sent in any chat using the inline mode, showing a
+// calendar or one of its dates with the buttons to join them
+type inlineCard struct {
+	Invitation string        `json:"invitation"`
+	Date       FormattedDate `json:"date,omitempty"` // empty for the cards of the whole calendar
+}
+
+// inlineCards are the cards sent by inline message ID, kept to update their counts
+var inlineCards = map[string]inlineCard{}
+
+// NewCard creates the card of the given kind
+func NewCard(kind, invitation string, date FormattedDate) inlineCard {
+	if kind == CALENDAR_CARD {
+		date = ""
+	}
+	return inlineCard{Invitation: invitation, Date: date}
+}
+
+// ParseCardID reads the card from the ID of the inline result used to send it
+func ParseCardID(resultID string) inlineCard {
+	var invitation, date, _ = strings.Cut(resultID, " ")
+	return inlineCard{Invitation: invitation, Date: FormattedDate(date)}
+}
+
+// ID is used as the ID of the inline result that sends the card
+func (card inlineCard) ID() string {
+	return strings.TrimSpace(card.Invitation + " " + string(card.Date))
+}
+
+// TrackCard starts updating the card sent with the given inline message ID
+func TrackCard(inlineMessageID string, card inlineCard) {
+	if inlineMessageID != "" && card.Invitation != "" {
+		inlineCards[inlineMessageID] = card
+	}
+}
+
+// cardEdits are the new contents of the cards waiting to be sent, keeping only the
+// latest one of each card, it has its own lock because the edits are sent by a
+// single goroutine outside of the dataLock
+var cardEdits = struct {
+	sync.Mutex
+	pending map[string]cardEdit
+	sending bool
+}{pending: map[string]cardEdit{}}
+
+// cardEdit is the content a card will be updated with
+type cardEdit struct {
+	text string
+	kbd  [][]tgui.InlineButton
+}
+
+// RefreshCards updates all the cards of the calendar without waiting for Telegram,
+// the ones whose content is not available anymore are marked as expired and no longer updated
+func RefreshCards(calendar *Calendar) {
+	if calendar == nil {
+		return
+	}
+
+	var edits = map[string]cardEdit{}
+	for inlineMessageID, card := range inlineCards {
+		if card.Invitation != calendar.invitation {
+			continue
+		}
+
+		text, kbd, ok := buildCard(card)
+		if !ok {
+			delete(inlineCards, inlineMessageID)
+		}
+		edits[inlineMessageID] = cardEdit{text, kbd}
+	}
+	if len(edits) == 0 {
+		return
+	}
+
+	cardEdits.Lock()
+	defer cardEdits.Unlock()
+	for inlineMessageID, edit := range edits {
+		cardEdits.pending[inlineMessageID] = edit
+	}
+	if !cardEdits.sending {
+		cardEdits.sending = true
+		go sendCardEdits()
+	}
+}
+
+// sendCardEdits edits the cards until there are no more pending updates, being
+// the only sender a card can't be overwritten by one of its older contents
+func sendCardEdits() {
+	for {
+		cardEdits.Lock()
+		if len(cardEdits.pending) == 0 {
+			cardEdits.sending = false
+			cardEdits.Unlock()
+			return
+		}
+		var inlineMessageID string
+		var edit cardEdit
+		for inlineMessageID, edit = range cardEdits.pending {
+			break
+		}
+		delete(cardEdits.pending, inlineMessageID)
+		cardEdits.Unlock()
+
+		telegram.EditInline(inlineMessageID, edit.text, genDefaultEditOpt(edit.kbd...))
+	}
+}
+
+// buildInlineResults proposes the calendar and its upcoming dates matching the query
+func buildInlineResults(lang Language, c Calendar, query string) (results []echotron.InlineQueryResult) {
+	var (
+		dates = DateFilter(FILTER_FUTURE).Apply(c, 0)
+		words = strings.Fields(strings.ToLower(query))
+	)
+
+	var propose = func(card inlineCard, title, description string) {
+		var searchable = strings.ToLower(fmt.Sprint(title, " ", description, " ", card.Date))
+		for _, word := range words {
+			if !strings.Contains(searchable, word) {
+				return
+			}
+		}
+		if len(results) >= INLINE_RESULTS {
+			return
+		}
+
+		text, kbd, _ := buildCard(card)
+		article := echotron.InlineQueryResultArticle{
+			Type:        echotron.InlineArticle,
+			ID:          card.ID(),
+			Title:       title,
+			Description: description,
+			InputMessageContent: echotron.InputTextMessageContent{
+				MessageText:           text,
+				ParseMode:             "HTML",
+				DisableWebPagePreview: true,
+			},
+		}
+		if len(kbd) > 0 {
+			article.ReplyMarkup = tgui.InlineKeyboard(kbd)
+		}
+		results = append(results, article)
+	}
+
+	propose(NewCard(CALENDAR_CARD, c.invitation, ""), CALENDAR.Text(c.name), lang.Plural("inline.dates", len(dates)))
+	for _, date := range dates {
+		propose(
+			NewCard(EVENT_CARD, c.invitation, date),
+			EVENT.Text(c.Describe(date, lang)),
+			fmt.Sprint(c.name, " - ", lang.Plural("day.attendee", c.CountAttendee(date))),
+		)
+	}
+	return
+}
+
+// buildCard writes the content of a card in the language of the organizer,
+// ok is false when the calendar or the date are not available anymore
+func buildCard(card inlineCard) (text string, kbd [][]tgui.InlineButton, ok bool) {
+	var (
+		lang     = DEFAULT_LANGUAGE
+		calendar = retreiveCalendar(card.Invitation)
+	)
+	if ownerID := retreiveOwner(card.Invitation); ownerID != nil {
+		lang = LanguageOf(*ownerID)
+	}
+	if calendar == nil || (card.Date != "" && calendar.dates[card.Date] == nil) {
+		return lang.T("card.expired"), nil, false
+	}
+
+	if card.Date == "" {
+		text = lang.T("card.calendar", html.EscapeString(calendar.name), html.EscapeString(calendar.description))
+		dates := DateFilter(FILTER_FUTURE).Apply(*calendar, 0)
+		if len(dates) == 0 {
+			text += lang.T("card.no_dates")
+		} else {
+			text += lang.T("card.pick_date")
+		}
+		if len(dates) > DATE_LIST_SIZE {
+			dates = dates[:DATE_LIST_SIZE]
+		}
+		for _, date := range dates {
+			kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(cardCaption(lang, *calendar, date), "/join", card.Invitation, string(date), CALENDAR_CARD)))
+		}
+	} else {
+		text = buildEventText(lang, *calendar, card.Date)
+		kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(lang.T("btn.join"), "/join", card.Invitation, string(card.Date), EVENT_CARD)))
+	}
+
+	if link := publicLink(GetShareLink(telegram.Username(), *calendar)); link != "" {
+		kbd = append(kbd, tgui.Wrap(tgui.InlineLink(lang.T("btn.open_calendar"), link)))
+	}
+	return text, kbd, true
+}
+
+// cardCaption is the caption of the button to join a date of a calendar card
+func cardCaption(lang Language, c Calendar, date FormattedDate) (caption string) {
+	caption = fmt.Sprint(c.Describe(date, lang), " - ", PEOPLE, c.CountAttendee(date))
+	if c.capacity > 0 {
+		caption += fmt.Sprint("/", c.capacity)
+	}
+	if !c.HasFreeSeats(date) {
+		caption = BLOCK.Text(caption)
+	}
+	return
+}
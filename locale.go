@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* --- LANGUAGE --- */
+
+// Language is the IETF code of one of the languages supported by the bot
+type Language string
+
+const (
+	ENGLISH Language = "en"
+	ITALIAN Language = "it"
+
+	DEFAULT_LANGUAGE = ENGLISH
+)
+
+// locale contains everything needed to speak a certain language
+type locale struct {
+	name      string
+	months    [12]string
+	weekdays  [7]string         // starting from Sunday
+	weekStart time.Weekday      // first day of the week
+	plural    func(n int) int   // index of the plural form to use for the given quantity
+	texts     map[string]string // message catalog, plural forms are separated by "|"
+}
+
+// locales is the list of all the supported languages
+var locales = map[Language]locale{
+	ENGLISH: english,
+	ITALIAN: italian,
+}
+
+// Languages returns the codes of all the supported languages sorted alphabetically
+func Languages() []Language {
+	return []Language{ENGLISH, ITALIAN}
+}
+
+// ParseLanguage grabs the language from an IETF language tag (like the one given by Telegram)
+// falling back to the default one when not supported
+func ParseLanguage(code string) Language {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if ind := strings.IndexAny(code, "-_"); ind > 0 {
+		code = code[:ind]
+	}
+
+	if _, ok := locales[Language(code)]; ok {
+		return Language(code)
+	}
+	return DEFAULT_LANGUAGE
+}
+
+func (l Language) locale() locale {
+	if loc, ok := locales[l]; ok {
+		return loc
+	}
+	return locales[DEFAULT_LANGUAGE]
+}
+
+// Name returns the name of the language in the language itself
+func (l Language) Name() string {
+	return l.locale().name
+}
+
+// T translates the text with the given key, formatting it with the args when given
+func (l Language) T(key string, args ...interface{}) string {
+	var text, ok = l.locale().texts[key]
+	if !ok {
+		if text, ok = locales[DEFAULT_LANGUAGE].texts[key]; !ok {
+			return key
+		}
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Plural translates the text with the given key choosing the right form for the quantity n,
+// the quantity is always the first argument used to format the text
+func (l Language) Plural(key string, n int, args ...interface{}) string {
+	var (
+		forms = strings.Split(l.T(key), "|")
+		ind   = l.locale().plural(n)
+	)
+
+	if ind >= len(forms) {
+		ind = len(forms) - 1
+	}
+	if !strings.Contains(forms[ind], "%") {
+		return forms[ind]
+	}
+	return fmt.Sprintf(forms[ind], append([]interface{}{n}, args...)...)
+}
+
+// Error translates the message of the given error if it's a CalendarError
+func (l Language) Error(err error) string {
+	if e, ok := err.(CalendarError); ok {
+		return l.T(string(e))
+	}
+	return err.Error()
+}
+
+func (l Language) Month(month time.Month) string {
+	return l.locale().months[month-1]
+}
+
+func (l Language) Weekday(day time.Weekday) string {
+	return l.locale().weekdays[day]
+}
+
+// Duration describes a duration using its two biggest units, ex: "2 days 3 hours"
+func (l Language) Duration(d time.Duration) string {
+	var parts []string
+	for _, unit := range []struct {
+		key  string
+		size time.Duration
+	}{
+		{"duration.days", time.Hour * 24},
+		{"duration.hours", time.Hour},
+		{"duration.minutes", time.Minute},
+	} {
+		if n := int(d / unit.size); n > 0 && len(parts) < 2 {
+			parts = append(parts, l.Plural(unit.key, n))
+			d -= time.Duration(n) * unit.size
+		}
+	}
+
+	if len(parts) == 0 {
+		return l.Plural("duration.minutes", 0)
+	}
+	return strings.Join(parts, " ")
+}
+
+// WeekStart returns the first day of the week where the language is spoken
+func (l Language) WeekStart() time.Weekday {
+	return l.locale().weekStart
+}
+
+// Format is like time.Format but using the names of months and weekdays of the language
+func (l Language) Format(t time.Time, layout string) string {
+	var (
+		loc     = l.locale()
+		month   = loc.months[t.Month()-1]
+		weekday = loc.weekdays[t.Weekday()]
+	)
+
+	layout = strings.NewReplacer(
+		"January", "\x00M",
+		"Jan", "\x00m",
+		"Monday", "\x00W",
+		"Mon", "\x00w",
+	).Replace(layout)
+
+	return strings.NewReplacer(
+		"\x00M", month,
+		"\x00m", short(month),
+		"\x00W", weekday,
+		"\x00w", short(weekday),
+	).Replace(t.Format(layout))
+}
+
+// short abbreviates a name of a month or a weekday
+func short(name string) string {
+	if runes := []rune(name); len(runes) > 3 {
+		return string(runes[:3])
+	}
+	return name
+}
+
+// singularPlural is the plural rule of languages having only "one" and "other" forms
+func singularPlural(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
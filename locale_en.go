@@ -0,0 +1,323 @@
+package main
+
+import "time"
+
+var english = locale{
+	name:      "English",
+	months:    [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	weekdays:  [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	weekStart: time.Sunday,
+	plural:    singularPlural,
+	texts: map[string]string{
+		/* --- BUTTONS --- */
+		"btn.add_event":        "➕ Add event",
+		"btn.all_day":          "All-day event",
+		"btn.add_events":       "➕ Add events",
+		"btn.back":             "Back",
+		"btn.cancel":           "Cancel",
+		"btn.close":            "Close",
+		"btn.confirm":          "Confirm",
+		"btn.create_calendar":  "🆕 Create new calendar",
+		"btn.edit_calendar":    "📝 Edit calendar",
+		"btn.invite_users":     "📨 Invite users",
+		"btn.stats":            "📊 Statistics",
+		"btn.templates":        "⧉ Templates",
+		"btn.save_template":    "Save as template",
+		"btn.select_days":      "Select days",
+		"btn.pick_time":        "Pick the time",
+		"btn.clear":            "Clear",
+		"btn.chart_joins":      "📈 Joins",
+		"btn.chart_weekdays":   "📅 Weekdays",
+		"btn.chart_hours":      "🕒 Times",
+		"btn.notification_off": "Turn off notifications",
+		"btn.new_token":        "🔑 New token",
+		"btn.revoke_token":     "🗑 Revoke token",
+		"btn.settings":         "Settings",
+		"btn.week_numbers":     "#️⃣ Week numbers: %v",
+		"btn.refresh":          "Refresh",
+		"btn.today":            "Today",
+		"btn.turn_back":        "↩️ Turn %s back to %s",
+		"btn.changes":          "Changes",
+		"btn.webhooks":         "🔗 Webhooks",
+		"btn.test_webhook":     "🧪 Test %s",
+		"btn.remove_webhook":   "🗑 Remove %s",
+		"btn.delivery_log":     "📜 Delivery log",
+		"btn.new_password":     "🔑 New password",
+		"btn.revoke_password":  "🗑 Revoke password",
+		"btn.join":             "🙋 Join",
+		"btn.joined":           "Joined",
+		"btn.event_full":       "Full",
+		"btn.open_calendar":    "📅 All dates",
+		"btn.share_inline":     "📤 Share in a chat",
+		"btn.qr_code":          "🔳 QR code",
+
+		/* --- TOAST ALERTS --- */
+		"alert.cancelled":          "Operation cancelled",
+		"alert.date_added":         "Date: %v %s added to your calendar",
+		"alert.span_exists":        "There is already an event starting at the midnight of %s",
+		"alert.day_blocked":        "Cannot create an event in this day",
+		"alert.deleted":            "Deleted",
+		"alert.week_number":        "ISO week number %d",
+		"alert.week_numbers":       "ISO week numbers",
+		"alert.joined":             "You joined this event",
+		"alert.joined_overlapping": "You joined this event, but it overlaps one of %s that you joined too",
+		"alert.date_overlapping":   "%[2]s added, but it overlaps your event of %[3]s|%[2]s added, but it overlaps %[1]d of your events, like the one of %[3]s",
+		"alert.copied":             "Copy added: %s",
+		"alert.copy_exists":        "There is already an event on this day",
+		"alert.template_saved":     "Template saved: %s",
+		"alert.template_removed":   "Template deleted",
+		"alert.undone":             "Change undone",
+		"alert.language_set":       "Language set to %s",
+		"alert.rate_limited":       "Too many requests, slow down a bit",
+		"alert.token_revoked":      "API token revoked",
+		"alert.no_token":           "You don't have an API token",
+		"alert.webhook_removed":    "Webhook removed",
+		"alert.webhook_tested":     "Test event sent, check the delivery log",
+		"alert.password_revoked":   "CalDAV password revoked",
+		"alert.no_password":        "You don't have a CalDAV password",
+
+		/* --- ERRORS --- */
+		"error.already_joined":     "Event already joined",
+		"error.event_full":         "There are no more free seats for this event",
+		"error.invalid_calendar":   "Empty calendar, invitation might be expired",
+		"error.invalid_date":       "Invalid date: %v",
+		"error.invalid_event":      "This date is not available anymore",
+		"error.invalid_rating":     "The rating must be between 1 and 5",
+		"error.not_rated":          "Rate the event before commenting it",
+		"error.invalid_code":       "This check-in code is not valid, it might be expired",
+		"error.already_checked_in": "You have already checked in this event",
+		"error.checkin_closed":     "The check-in of this event can not be opened now",
+		"error.overlapping":        "This event overlaps others that you joined",
+		"error.invalid_template":   "This template or event does not exist anymore",
+		"error.too_many_templates": "You reached the max number of templates, delete one of them first",
+		"error.invalid_change":     "This change is not in the log anymore",
+		"error.not_reversible":     "This change can not be undone anymore",
+		"error.no_selection":       "Select at least a day first",
+		"error.invalid_field":      "This field can not be edited",
+		"error.invalid_value":      "Invalid value for this field",
+		"error.invalid_invitation": "Invalid invitation",
+		"error.invalid_joining":    "Invalid joining: %s",
+		"error.invalid_link":       "Invalid invitation link",
+		"error.no_calendar":        "You don't have a calendar yet, use the command /publish to create a new one",
+		"error.no_payload":         "No given payload",
+		"error.not_joined":         "You have not joined this event",
+		"error.invalid_webhook":    "Invalid webhook, use a full http or https URL",
+		"error.too_many_webhooks":  "You can't register more webhooks, remove one first",
+		"error.private_webhook":    "Invalid webhook, it must point to a public address",
+
+		/* --- CALENDAR --- */
+		"calendar.default_name":        "%s calendar",
+		"calendar.default_description": "%s personal event",
+		"capacity.unlimited":           "unlimited",
+		"reminder.week":                "Don't forget the %s, is coming soon: %s",
+		"reminder.tomorrow":            "Tomorrow there will be %s waiting for you! (%s)",
+		"reminder.generic":             "Don't forget the %s, it will start in %s: %s",
+		"duration.days":                "%d day|%d days",
+		"duration.hours":               "%d hour|%d hours",
+		"duration.minutes":             "%d minute|%d minutes",
+		"notification.joined":          "<b>+ 1</b>: %[2]s joined your event in date: %[3]s|<b>%[1]d attendees</b>: %[2]s just joined your event in date: %[3]s",
+
+		/* --- START --- */
+		"start.organizer": "<i>Hi! What can I do for you today?</i>\n" +
+			"Here is some infos about your calendar:" +
+			"\n%vnotification: <code>%v</code>" +
+			"\n🎟incoming events: %d" +
+			"\n🪑seats per event: <code>%s</code>" +
+			"\n%vpeople reached: %d" +
+			"\n🏷name: <code>%s</code>" +
+			"\n📑description: <code>%s</code>",
+		"start.welcome": "👋 <b>Welcome, I'm Calen-Daggerbill!</b> %v\n" +
+			"<i>Your <a href=\"https://github.com/DazFather/calendaggerbill\">open source</a>" +
+			" robo-hummingbird that will assist you to manage your calendar</i>" +
+			"\n\nUsing me is very easy and free:" +
+			"\n First of all you need to create a calendar, " +
+			"<i>use the button below or the command </i> /publish",
+
+		/* --- PUBLISH --- */
+		"publish.select_day":   "🗓 Select a day from the calendar: %s",
+		"publish.select_month": "Select a month of the year <b>%d</b>",
+		"publish.select_first": "🌞 Select the first day of the all-day event: %s",
+		"publish.select_last":  "🌞 Starting on <b>%s</b>, now select the last day, or the same one for a single day",
+		"publish.created": "<b>Your calendar has been created</b>\n" +
+			"Use the previous message or /publish again to add new available dates\n" +
+			"Send /edit to modify your calendar's settings like name, description and notification\n" +
+			"Share the following link to make people join your events: %s",
+		"day.title":          "Events on <b>%s</b>\n",
+		"day.attendee":       "%d attendee joined this event|%d attendees joined this event",
+		"day.overlapping":    "overlapping",
+		"date.all_day":       "%s, all day",
+		"date.all_day_short": "all day",
+		"date.span":          "%s → %s",
+		"conflict.title":     "⚠️ <b>%s</b> - %s overlaps events that you already joined:\n",
+		"conflict.event":     "\n🎟 %s - %s",
+		"conflict.confirm":   "\n\n<b>Do you want to join it anyway?</b>",
+		"btn.join_anyway":    "Join anyway",
+
+		/* --- DATE LIST --- */
+		"list.title":       "<b>%s</b>\n%s\n\n<i>Tap one (or more) of following dates to join</i>",
+		"list.empty":       "\n\nThere are no dates matching the selected filters",
+		"list.page":        "Page %d of %d",
+		"list.you":         "(You)",
+		"filter.future":    "Future",
+		"filter.joined":    "Joined",
+		"filter.free":      "Free seats",
+		"view.calendar":    "Calendar",
+		"view.week":        "Week",
+		"view.agenda":      "Agenda",
+		"week.title":       "<b>%s</b>\n<i>Week %d, from %s to %s</i>",
+		"grid.week_number": "W%d",
+		"week.events":      "%d event on %s|%d events on %s",
+		"agenda.title":     "<b>%s</b>\n%s\n\n<i>Upcoming events from %s</i>\n",
+		"agenda.empty":     "No upcoming events",
+
+		/* --- EDIT --- */
+		"edit.ask_field": "What do you want to edit?\n" +
+			"<i>You can also do it in one go, ex:</i> <code>/edit name My new AMAZING✨ name</code>",
+		"edit.ask_name":             "Send the new name of the calendar\n<i>Now it is:</i> <code>%s</code>",
+		"edit.ask_description":      "Send the new description of the calendar\n<i>Now it is:</i> <code>%s</code>",
+		"edit.ask_notification":     "Do you want the notifications of the events?\n<i>Now they are:</i> <code>%s</code>",
+		"edit.ask_capacity":         "Send the max number of attendee per event, <code>0</code> means unlimited\n<i>Now it is:</i> <code>%s</code>",
+		"edit.ask_duration":         "Send how long the events last, ex: <code>1h30m</code>, it is used to warn about overlapping events\n<i>Now it is:</i> <code>%s</code>",
+		"field.name":                "Name",
+		"field.description":         "Description",
+		"field.notification":        "Notifications",
+		"field.capacity":            "Capacity",
+		"field.duration":            "Duration",
+		"btn.on":                    "On",
+		"btn.off":                   "Off",
+		"conversation.expired":      "Too much time has passed, the operation has been cancelled",
+		"conversation.none":         "There is no operation to cancel",
+		"edit.invalid_notification": "Invalid specifier for this command (%s), use <code>on</code>, <code>off</code> instead",
+		"edit.invalid_capacity":     "Invalid specifier for this command (%s), use a positive number or <code>0</code> instead",
+		"edit.invalid_duration":     "Invalid specifier for this command (%s), use a duration like <code>2h</code> or <code>1h30m</code> instead",
+		"edit.invalid_field":        "Invalid specifier for this command: \"<i>%s</i>\", use <code>name</code>, <code>description</code>, <code>capacity</code> or <code>duration</code> instead",
+		"edit.confirm": "Your calendar's %s will change\n" +
+			"<i>from:</i> <code>%s</code>\n" +
+			"<i>to:</i> <code>%s</code>\n" +
+			"\n<b>Confirm the change?</b>",
+		"set.no_calendar": "Unable to set: no calendar found",
+		"set.done":        "<b>Your calendar has been edited</b>\nCalendar's %s successfully changed to:\n %s",
+		"set.warned":      ", the only attendee has been warned|, all %d attendees have been warned",
+		"set.warning":     "The %s of a calendar that you have joined changed:\n<i>%s</i> ➡️ <b>%s</b>",
+
+		/* --- OTHERS --- */
+		"link.show":                 "Your link: %s",
+		"link.event":                "Link to the event of %s, tap to copy it:\n<code>%s</code>",
+		"feedback.request":          "How was <b>%s</b> on %s?\n<i>Rate it from 1 to 5 stars</i>",
+		"feedback.rated":            "Thanks for your %s!\nSend a message to add a comment, or skip it",
+		"feedback.thanks":           "Thanks for your feedback",
+		"feedback.summary":          "<b>%[2]s</b>\n⭐ %.1[3]f average of %[1]d rating\n\n<i>Tap a date to see its ratings and comments</i>|<b>%[2]s</b>\n⭐ %.1[3]f average of %[1]d ratings\n\n<i>Tap a date to see its ratings and comments</i>",
+		"feedback.none":             "<b>%s</b>\nNo ratings received yet",
+		"feedback.detail":           "<b>%[2]s</b> - %[3]s\n⭐ %.1[4]f average of %[1]d rating (👥 %[5]d joined)\n|<b>%[2]s</b> - %[3]s\n⭐ %.1[4]f average of %[1]d ratings (👥 %[5]d joined)\n",
+		"btn.skip_comment":          "Skip",
+		"history.title":             "<b>%[2]s</b>\n%[1]d event occurred, tap it to see who attended\n|<b>%[2]s</b>\n%[1]d events occurred, tap one to see who attended\n",
+		"history.empty":             "<i>No event has occurred yet</i>",
+		"history.detail":            "<b>%[2]s</b> - %[3]s\n👥 %[1]d attendee joined this event\n|<b>%[2]s</b> - %[3]s\n👥 %[1]d attendees joined this event\n",
+		"history.attended":          "✅ %d attendee checked in\n|✅ %d attendees checked in\n",
+		"history.attendee":          "\n%d. %s<a href=\"tg://user?id=%[3]d\">%[3]d</a>",
+		"history.walk_ins":          "\n\n<b>Checked in without joining</b>",
+		"change.title":              "<b>%[2]s</b>\n%[1]d change, tap ↩️ to undo it\n|<b>%[2]s</b>\nLast %[1]d changes, tap ↩️ and the number of one to undo it\n",
+		"change.empty":              "<i>No change has been made yet</i>",
+		"change.entry":              "\n<b>%d.</b> <i>%s</i>\n%s",
+		"change.you":                "You",
+		"change.user":               "<a href=\"tg://user?id=%[1]d\">%[1]d</a>",
+		"change.edited":             "%s changed the %s: <i>%s</i> ➡️ <b>%s</b>",
+		"change.added":              "%s added %s",
+		"change.removed":            "%[2]s removed %[3]s with %[1]d attendee|%[2]s removed %[3]s with %[1]d attendees",
+		"change.joined":             "%s joined %s",
+		"change.joined_by":          "%s added %s to %s",
+		"change.left":               "%s left %s",
+		"change.left_by":            "%s removed %s from %s",
+		"change.undone":             " <i>(undone)</i>",
+		"change.confirm":            "↩️ <b>Undo this change?</b>\n%s",
+		"change.will_warn":          "\n\n<i>The only attendee involved will be warned</i>|\n\n<i>The %d attendees involved will be warned</i>",
+		"undo.cancelled":            "The event of <b>%s</b> on %s has been cancelled",
+		"undo.restored":             "The event of <b>%s</b> on %s is back, and you are still among its attendees",
+		"undo.left":                 "You are no longer among the attendees of <b>%s</b> on %s",
+		"undo.joined":               "You are again among the attendees of <b>%s</b> on %s",
+		"checkin.help":              "<b>Check-in</b>\nOpen the check-in of an event to show a code that changes every few minutes, attendees are marked present sending <code>/checkin</code> followed by the code or scanning its QR code\n\n",
+		"checkin.none":              "<i>No event can be checked in now, the check-in can be opened from an hour before the start</i>",
+		"checkin.pick":              "<i>Tap an event to open its check-in</i>",
+		"checkin.show":              "<b>Check-in of %s</b> - %s\n\n🔑 Code: <code>%s</code>\n<i>It changes at %s, refresh to see the new one</i>\n\n✅ %d checked in\n🙋 %d joined\n🔒 Open until %s",
+		"checkin.qr":                "Scan to check in %s",
+		"checkin.closed":            "The check-in has been closed",
+		"checkin.usage":             "Send <code>/checkin</code> followed by the code shown by the organizer to be marked present",
+		"checkin.done":              "Checked in to <b>%s</b> - %s",
+		"btn.roster":                "Roster",
+		"btn.close_checkin":         "Close check-in",
+		"btn.checkin":               "Check-in",
+		"stats.title":               "📊 <b>Statistics of %s</b>\n",
+		"template.title":            "⧉ <b>Templates of %s</b>\n",
+		"template.empty":            "\n<i>No templates yet, save one from the ⧉ button of an event in the day view of /publish</i>",
+		"template.help":             "\n\nTap a template to add it to the days you select in the calendar, use <code>/template rename</code> followed by its number and the new name to rename it",
+		"template.timed":            "Event at %s",
+		"template.all_day":          "All-day event|All-day event of %d days",
+		"copy.select":               "Select the days where to add <b>%s</b>: %s",
+		"select.title":              "Tap the days to add, all at the same time: %[2]s\n<i>%[1]d day selected</i>|Tap the days to add, all at the same time: %[2]s\n<i>%[1]d days selected</i>",
+		"select.hour":               "Select the hour of the selected day|Select the hour of the %d selected days",
+		"select.minute":             "Select the minutes after %s",
+		"select.review":             "<b>Adding the selected day at %[2]s:</b>\n|<b>Adding the %[1]d selected days at %[2]s:</b>\n",
+		"select.existing":           "<i>(already in the calendar)</i>",
+		"select.added":              "<b>%d date added</b>\n|<b>%d dates added</b>\n",
+		"select.already":            "\n\n<b>%d date was already in the calendar:</b>|\n\n<b>%d dates were already in the calendar:</b>",
+		"select.passed":             "\n\n<b>%d date skipped because already passed:</b>|\n\n<b>%d dates skipped because already passed:</b>",
+		"stats.events":              "\n📅 %d upcoming and %d past events",
+		"stats.joins":               "\n📈 %d joins in the last %d weeks",
+		"stats.attendance":          "\n👥 %.1f attendees per past event on average",
+		"stats.no_shows":            "\n🙈 %.0[2]f%% of no-shows in the event with a check-in|\n🙈 %.0[2]f%% of no-shows in the %[1]d events with a check-in",
+		"stats.repeat":              "\n🔁 %d of %d attendees joined more than one event",
+		"stats.conversion":          "\n🔗 Invitations opened %d times by %d users, %d of them joined (%.0f%%)",
+		"stats.popular":             "\n🏆 Most popular: %s at %02d:00",
+		"stats.chart.joins":         "📈 Joins per week to %s",
+		"stats.chart.weekdays":      "📅 Attendees by weekday of the events of %s",
+		"stats.chart.hours":         "🕒 Attendees by starting hour of the events of %s",
+		"btn.ratings":               "Ratings",
+		"link.calendar_feed":        "\n\n📅 Subscribe to your calendar from Google, Apple or Thunderbird calendar apps:\n<code>%s</code>",
+		"link.attendee_feed":        "\n\n🙋 Subscribe to the events you joined:\n<code>%s</code>\n<i>Keep these links private</i>",
+		"inline.dates":              "%d upcoming date|%d upcoming dates",
+		"card.calendar":             "📅 <b>%s</b>\n%s\n\n",
+		"event.show":                "🎟 <b>%s</b>\n%s\n\n🕒 %s\n👥 %s",
+		"event.seats":               "\n🪑 %d free seat|\n🪑 %d free seats",
+		"card.no_dates":             "<i>There are no upcoming dates</i>",
+		"card.pick_date":            "<i>Tap a date to join</i>",
+		"card.expired":              "⌛️ This event is not available anymore",
+		"feed.attendee_name":        "Joined events",
+		"feed.attendee_description": "Events joined with CalenDaggerbill",
+		"language.select":           "Select the language you prefer",
+		"token.show": "<b>API token</b>\n" +
+			"Use it to manage your calendar from your own tools through the HTTP API, " +
+			"its description is available at <code>/api/openapi.json</code>\n" +
+			"Issuing a new token invalidates the previous one",
+		"token.disabled": "\n\n⚠️ The HTTP API is not enabled on this bot",
+		"token.issued": "🔑 Your new API token is:\n<code>%s</code>\n\n" +
+			"<i>Keep it secret, it gives full access to your calendar. It will not be shown again</i>",
+		"webhook.title": "<b>Webhooks</b>\n" +
+			"The events of your calendar (joins, leaves, dates added or removed, edits and reminders) are sent as JSON to these URLs.\n" +
+			"Send <code>/webhook add https://example.com/hook</code> to register a new one\n",
+		"webhook.empty": "\n<i>No webhook registered yet</i>",
+		"webhook.help":  "Use <code>/webhook add</code> followed by the URL of the webhook",
+		"webhook.added": "<b>Webhook <code>%s</code> registered</b>\n%s\n\n" +
+			"Secret used to sign the events: <code>%s</code>\n" +
+			"<i>The header X-Calendaggerbill-Signature contains the HMAC-SHA256 of the body using the secret</i>",
+		"webhook.log":       "<b>Last deliveries</b>\n",
+		"webhook.log_empty": "\n<i>No deliveries yet</i>",
+		"webhook.delivery":  "%s <code>%s</code> ➡️ %s (attempt %d, status %d)",
+		"caldav.show": "<b>CalDAV</b>\n" +
+			"Add an account with these settings to your calendar app to see and edit your events from there\n" +
+			"\n🌐server: <code>%s</code>" +
+			"\n👤username: <code>%d</code>",
+		"caldav.password": "\n🔑password: <code>%s</code>\n\n" +
+			"<i>Keep it secret, it will not be shown again</i>",
+		"caldav.disabled": "CalDAV is not enabled on this bot",
+		"status.show": "<b>Bot status</b>\n" +
+			"\n📅calendars: %d" +
+			"\n🎟incoming events: %d" +
+			"\n👤known users: %d" +
+			"\n💾storage: <code>%s</code>" +
+			"\n🕒time zone: <code>%s</code>",
+		"settings.show": "<b>Your preferences</b>\n" +
+			"\n🌐language: <code>%s</code>" +
+			"\n📆week starts on: <code>%s</code>" +
+			"\n#️⃣week numbers: <code>%v</code>",
+	},
+}
@@ -0,0 +1,323 @@
+package main
+
+import "time"
+
+var italian = locale{
+	name:      "Italiano",
+	months:    [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+	weekdays:  [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+	weekStart: time.Monday,
+	plural:    singularPlural,
+	texts: map[string]string{
+		/* --- BUTTONS --- */
+		"btn.add_event":        "➕ Aggiungi evento",
+		"btn.all_day":          "Evento di giornata intera",
+		"btn.add_events":       "➕ Aggiungi eventi",
+		"btn.back":             "Indietro",
+		"btn.cancel":           "Annulla",
+		"btn.close":            "Chiudi",
+		"btn.confirm":          "Conferma",
+		"btn.create_calendar":  "🆕 Crea un nuovo calendario",
+		"btn.edit_calendar":    "📝 Modifica calendario",
+		"btn.invite_users":     "📨 Invita utenti",
+		"btn.stats":            "📊 Statistiche",
+		"btn.templates":        "⧉ Modelli",
+		"btn.save_template":    "Salva come modello",
+		"btn.select_days":      "Seleziona giorni",
+		"btn.pick_time":        "Scegli l'orario",
+		"btn.clear":            "Svuota",
+		"btn.chart_joins":      "📈 Iscrizioni",
+		"btn.chart_weekdays":   "📅 Giorni",
+		"btn.chart_hours":      "🕒 Orari",
+		"btn.notification_off": "Disattiva notifiche",
+		"btn.new_token":        "🔑 Nuovo token",
+		"btn.revoke_token":     "🗑 Revoca token",
+		"btn.settings":         "Impostazioni",
+		"btn.week_numbers":     "#️⃣ Numeri delle settimane: %v",
+		"btn.refresh":          "Aggiorna",
+		"btn.today":            "Oggi",
+		"btn.turn_back":        "↩️ Riporta %s a %s",
+		"btn.changes":          "Modifiche",
+		"btn.webhooks":         "🔗 Webhook",
+		"btn.test_webhook":     "🧪 Prova %s",
+		"btn.remove_webhook":   "🗑 Rimuovi %s",
+		"btn.delivery_log":     "📜 Registro invii",
+		"btn.new_password":     "🔑 Nuova password",
+		"btn.revoke_password":  "🗑 Revoca password",
+		"btn.join":             "🙋 Partecipa",
+		"btn.joined":           "Partecipi già",
+		"btn.event_full":       "Al completo",
+		"btn.open_calendar":    "📅 Tutte le date",
+		"btn.share_inline":     "📤 Condividi in una chat",
+		"btn.qr_code":          "🔳 Codice QR",
+
+		/* --- TOAST ALERTS --- */
+		"alert.cancelled":          "Operazione annullata",
+		"alert.date_added":         "Data: %v %s aggiunta al tuo calendario",
+		"alert.span_exists":        "C'è già un evento che inizia alla mezzanotte di %s",
+		"alert.day_blocked":        "Non puoi creare eventi in questo giorno",
+		"alert.deleted":            "Eliminato",
+		"alert.week_number":        "Settimana ISO numero %d",
+		"alert.week_numbers":       "Numeri delle settimane ISO",
+		"alert.joined":             "Ti sei unito a questo evento",
+		"alert.joined_overlapping": "Ti sei unito a questo evento, ma si sovrappone a uno di %s a cui partecipi",
+		"alert.date_overlapping":   "%[2]s aggiunto, ma si sovrappone al tuo evento del %[3]s|%[2]s aggiunto, ma si sovrappone a %[1]d tuoi eventi, come quello del %[3]s",
+		"alert.copied":             "Copia aggiunta: %s",
+		"alert.copy_exists":        "C'è già un evento in questo giorno",
+		"alert.template_saved":     "Modello salvato: %s",
+		"alert.template_removed":   "Modello eliminato",
+		"alert.undone":             "Modifica annullata",
+		"alert.language_set":       "Lingua impostata: %s",
+		"alert.rate_limited":       "Troppe richieste, rallenta un po'",
+		"alert.token_revoked":      "Token API revocato",
+		"alert.no_token":           "Non hai un token API",
+		"alert.webhook_removed":    "Webhook rimosso",
+		"alert.webhook_tested":     "Evento di prova inviato, controlla il registro invii",
+		"alert.password_revoked":   "Password CalDAV revocata",
+		"alert.no_password":        "Non hai una password CalDAV",
+
+		/* --- ERRORS --- */
+		"error.already_joined":     "Ti sei già unito a questo evento",
+		"error.event_full":         "Non ci sono più posti liberi per questo evento",
+		"error.invalid_calendar":   "Calendario vuoto, l'invito potrebbe essere scaduto",
+		"error.invalid_date":       "Data non valida: %v",
+		"error.invalid_event":      "Questa data non è più disponibile",
+		"error.invalid_rating":     "Il voto deve essere tra 1 e 5",
+		"error.not_rated":          "Vota l'evento prima di commentarlo",
+		"error.invalid_code":       "Questo codice di check-in non è valido, potrebbe essere scaduto",
+		"error.already_checked_in": "Hai già fatto il check-in per questo evento",
+		"error.checkin_closed":     "Il check-in di questo evento non può essere aperto ora",
+		"error.overlapping":        "Questo evento si sovrappone ad altri a cui partecipi",
+		"error.invalid_template":   "Questo modello o evento non esiste più",
+		"error.too_many_templates": "Hai raggiunto il numero massimo di modelli, eliminane prima uno",
+		"error.invalid_change":     "Questa modifica non è più nel registro",
+		"error.not_reversible":     "Questa modifica non può più essere annullata",
+		"error.no_selection":       "Seleziona prima almeno un giorno",
+		"error.invalid_field":      "Questo campo non può essere modificato",
+		"error.invalid_value":      "Valore non valido per questo campo",
+		"error.invalid_invitation": "Invito non valido",
+		"error.invalid_joining":    "Partecipazione non valida: %s",
+		"error.invalid_link":       "Link di invito non valido",
+		"error.no_calendar":        "Non hai ancora un calendario, usa il comando /publish per crearne uno",
+		"error.no_payload":         "Nessun parametro fornito",
+		"error.not_joined":         "Non partecipi a questo evento",
+		"error.invalid_webhook":    "Webhook non valido, usa un URL http o https completo",
+		"error.too_many_webhooks":  "Non puoi registrare altri webhook, prima rimuovine uno",
+		"error.private_webhook":    "Webhook non valido, deve puntare a un indirizzo pubblico",
+
+		/* --- CALENDAR --- */
+		"calendar.default_name":        "Calendario di %s",
+		"calendar.default_description": "Eventi personali di %s",
+		"capacity.unlimited":           "illimitati",
+		"reminder.week":                "Non dimenticare %s, manca poco: %s",
+		"reminder.tomorrow":            "Domani ci sarà %s ad aspettarti! (%s)",
+		"reminder.generic":             "Non dimenticare %s, inizierà tra %s: %s",
+		"duration.days":                "%d giorno|%d giorni",
+		"duration.hours":               "%d ora|%d ore",
+		"duration.minutes":             "%d minuto|%d minuti",
+		"notification.joined":          "<b>+ 1</b>: %[2]s si è unito al tuo evento in data: %[3]s|<b>%[1]d partecipanti</b>: %[2]s si è appena unito al tuo evento in data: %[3]s",
+
+		/* --- START --- */
+		"start.organizer": "<i>Ciao! Cosa posso fare per te oggi?</i>\n" +
+			"Ecco qualche informazione sul tuo calendario:" +
+			"\n%vnotifiche: <code>%v</code>" +
+			"\n🎟eventi in arrivo: %d" +
+			"\n🪑posti per evento: <code>%s</code>" +
+			"\n%vpersone raggiunte: %d" +
+			"\n🏷nome: <code>%s</code>" +
+			"\n📑descrizione: <code>%s</code>",
+		"start.welcome": "👋 <b>Benvenuto, sono Calen-Daggerbill!</b> %v\n" +
+			"<i>Il tuo robo-colibrì <a href=\"https://github.com/DazFather/calendaggerbill\">open source</a>" +
+			" che ti aiuterà a gestire il tuo calendario</i>" +
+			"\n\nUsarmi è facile e gratuito:" +
+			"\n Prima di tutto devi creare un calendario, " +
+			"<i>usa il pulsante qui sotto o il comando </i> /publish",
+
+		/* --- PUBLISH --- */
+		"publish.select_day":   "🗓 Seleziona un giorno dal calendario: %s",
+		"publish.select_month": "Seleziona un mese dell'anno <b>%d</b>",
+		"publish.select_first": "🌞 Seleziona il primo giorno dell'evento di una giornata intera: %s",
+		"publish.select_last":  "🌞 A partire da <b>%s</b>, ora seleziona l'ultimo giorno, o lo stesso per un solo giorno",
+		"publish.created": "<b>Il tuo calendario è stato creato</b>\n" +
+			"Usa il messaggio precedente o di nuovo /publish per aggiungere nuove date disponibili\n" +
+			"Invia /edit per modificare le impostazioni del calendario come nome, descrizione e notifiche\n" +
+			"Condividi il seguente link per far partecipare le persone ai tuoi eventi: %s",
+		"day.title":          "Eventi di <b>%s</b>\n",
+		"day.attendee":       "%d partecipante a questo evento|%d partecipanti a questo evento",
+		"day.overlapping":    "sovrapposto",
+		"date.all_day":       "%s, tutto il giorno",
+		"date.all_day_short": "tutto il giorno",
+		"date.span":          "%s → %s",
+		"conflict.title":     "⚠️ <b>%s</b> - %s si sovrappone a eventi a cui partecipi già:\n",
+		"conflict.event":     "\n🎟 %s - %s",
+		"conflict.confirm":   "\n\n<b>Vuoi partecipare comunque?</b>",
+		"btn.join_anyway":    "Partecipa comunque",
+
+		/* --- DATE LIST --- */
+		"list.title":       "<b>%s</b>\n%s\n\n<i>Tocca una (o più) delle seguenti date per partecipare</i>",
+		"list.empty":       "\n\nNon ci sono date che corrispondono ai filtri selezionati",
+		"list.page":        "Pagina %d di %d",
+		"list.you":         "(Tu)",
+		"filter.future":    "Future",
+		"filter.joined":    "Partecipo",
+		"filter.free":      "Posti liberi",
+		"view.calendar":    "Calendario",
+		"view.week":        "Settimana",
+		"view.agenda":      "Agenda",
+		"week.title":       "<b>%s</b>\n<i>Settimana %d, dal %s al %s</i>",
+		"grid.week_number": "S%d",
+		"week.events":      "%d evento di %s|%d eventi di %s",
+		"agenda.title":     "<b>%s</b>\n%s\n\n<i>Prossimi eventi dal %s</i>\n",
+		"agenda.empty":     "Nessun evento in programma",
+
+		/* --- EDIT --- */
+		"edit.ask_field": "Cosa vuoi modificare?\n" +
+			"<i>Puoi farlo anche in un colpo solo, es:</i> <code>/edit name Il mio nuovo FANTASTICO✨ nome</code>",
+		"edit.ask_name":             "Invia il nuovo nome del calendario\n<i>Ora è:</i> <code>%s</code>",
+		"edit.ask_description":      "Invia la nuova descrizione del calendario\n<i>Ora è:</i> <code>%s</code>",
+		"edit.ask_notification":     "Vuoi le notifiche degli eventi?\n<i>Ora sono:</i> <code>%s</code>",
+		"edit.ask_capacity":         "Invia il numero massimo di partecipanti per evento, <code>0</code> significa illimitati\n<i>Ora è:</i> <code>%s</code>",
+		"edit.ask_duration":         "Invia quanto durano gli eventi, es: <code>1h30m</code>, serve ad avvisare degli eventi sovrapposti\n<i>Ora è:</i> <code>%s</code>",
+		"field.name":                "Nome",
+		"field.description":         "Descrizione",
+		"field.notification":        "Notifiche",
+		"field.capacity":            "Capienza",
+		"field.duration":            "Durata",
+		"btn.on":                    "Attive",
+		"btn.off":                   "Disattivate",
+		"conversation.expired":      "È passato troppo tempo, l'operazione è stata annullata",
+		"conversation.none":         "Non c'è nessuna operazione da annullare",
+		"edit.invalid_notification": "Specificatore non valido per questo comando (%s), usa <code>on</code> o <code>off</code>",
+		"edit.invalid_capacity":     "Specificatore non valido per questo comando (%s), usa un numero positivo o <code>0</code>",
+		"edit.invalid_duration":     "Specificatore non valido per questo comando (%s), usa una durata come <code>2h</code> o <code>1h30m</code>",
+		"edit.invalid_field":        "Specificatore non valido per questo comando: \"<i>%s</i>\", usa <code>name</code>, <code>description</code>, <code>capacity</code> o <code>duration</code>",
+		"edit.confirm": "Il campo %s del tuo calendario cambierà\n" +
+			"<i>da:</i> <code>%s</code>\n" +
+			"<i>a:</i> <code>%s</code>\n" +
+			"\n<b>Confermi la modifica?</b>",
+		"set.no_calendar": "Impossibile modificare: nessun calendario trovato",
+		"set.done":        "<b>Il tuo calendario è stato modificato</b>\nIl campo %s è ora:\n %s",
+		"set.warned":      ", l'unico partecipante è stato avvisato|, tutti i %d partecipanti sono stati avvisati",
+		"set.warning":     "Il campo %s di un calendario a cui partecipi è cambiato:\n<i>%s</i> ➡️ <b>%s</b>",
+
+		/* --- OTHERS --- */
+		"link.show":                 "Il tuo link: %s",
+		"link.event":                "Link all'evento del %s, tocca per copiarlo:\n<code>%s</code>",
+		"feedback.request":          "Com'è andato <b>%s</b> il %s?\n<i>Votalo da 1 a 5 stelle</i>",
+		"feedback.rated":            "Grazie per le tue %s!\nInvia un messaggio per aggiungere un commento, oppure salta",
+		"feedback.thanks":           "Grazie per il tuo feedback",
+		"feedback.summary":          "<b>%[2]s</b>\n⭐ %.1[3]f di media su %[1]d voto\n\n<i>Tocca una data per vederne voti e commenti</i>|<b>%[2]s</b>\n⭐ %.1[3]f di media su %[1]d voti\n\n<i>Tocca una data per vederne voti e commenti</i>",
+		"feedback.none":             "<b>%s</b>\nNessun voto ricevuto per ora",
+		"feedback.detail":           "<b>%[2]s</b> - %[3]s\n⭐ %.1[4]f di media su %[1]d voto (👥 %[5]d partecipanti)\n|<b>%[2]s</b> - %[3]s\n⭐ %.1[4]f di media su %[1]d voti (👥 %[5]d partecipanti)\n",
+		"btn.skip_comment":          "Salta",
+		"history.title":             "<b>%[2]s</b>\n%[1]d evento passato, toccalo per vedere chi ha partecipato\n|<b>%[2]s</b>\n%[1]d eventi passati, toccane uno per vedere chi ha partecipato\n",
+		"history.empty":             "<i>Nessun evento è ancora passato</i>",
+		"history.detail":            "<b>%[2]s</b> - %[3]s\n👥 %[1]d partecipante a questo evento\n|<b>%[2]s</b> - %[3]s\n👥 %[1]d partecipanti a questo evento\n",
+		"history.attended":          "✅ %d partecipante ha fatto il check-in\n|✅ %d partecipanti hanno fatto il check-in\n",
+		"history.attendee":          "\n%d. %s<a href=\"tg://user?id=%[3]d\">%[3]d</a>",
+		"history.walk_ins":          "\n\n<b>Check-in senza iscrizione</b>",
+		"change.title":              "<b>%[2]s</b>\n%[1]d modifica, tocca ↩️ per annullarla\n|<b>%[2]s</b>\nUltime %[1]d modifiche, tocca ↩️ e il numero di una per annullarla\n",
+		"change.empty":              "<i>Non è stata fatta ancora nessuna modifica</i>",
+		"change.entry":              "\n<b>%d.</b> <i>%s</i>\n%s",
+		"change.you":                "Tu",
+		"change.user":               "<a href=\"tg://user?id=%[1]d\">%[1]d</a>",
+		"change.edited":             "%s: %s cambiato da <i>%s</i> ➡️ <b>%s</b>",
+		"change.added":              "%s: aggiunto %s",
+		"change.removed":            "%[2]s: rimosso %[3]s con %[1]d partecipante|%[2]s: rimosso %[3]s con %[1]d partecipanti",
+		"change.joined":             "%s partecipa a %s",
+		"change.joined_by":          "%s: aggiunto %s a %s",
+		"change.left":               "%s non partecipa più a %s",
+		"change.left_by":            "%s: rimosso %s da %s",
+		"change.undone":             " <i>(annullata)</i>",
+		"change.confirm":            "↩️ <b>Annullare questa modifica?</b>\n%s",
+		"change.will_warn":          "\n\n<i>L'unico partecipante coinvolto sarà avvisato</i>|\n\n<i>I %d partecipanti coinvolti saranno avvisati</i>",
+		"undo.cancelled":            "L'evento di <b>%s</b> del %s è stato annullato",
+		"undo.restored":             "L'evento di <b>%s</b> del %s è tornato, e sei ancora tra i partecipanti",
+		"undo.left":                 "Non sei più tra i partecipanti di <b>%s</b> del %s",
+		"undo.joined":               "Sei di nuovo tra i partecipanti di <b>%s</b> del %s",
+		"checkin.help":              "<b>Check-in</b>\nApri il check-in di un evento per mostrare un codice che cambia ogni pochi minuti, i partecipanti sono segnati presenti inviando <code>/checkin</code> seguito dal codice o scansionando il suo codice QR\n\n",
+		"checkin.none":              "<i>Nessun evento può fare il check-in ora, il check-in si può aprire da un'ora prima dell'inizio</i>",
+		"checkin.pick":              "<i>Tocca un evento per aprirne il check-in</i>",
+		"checkin.show":              "<b>Check-in di %s</b> - %s\n\n🔑 Codice: <code>%s</code>\n<i>Cambia alle %s, aggiorna per vedere il nuovo</i>\n\n✅ %d presenti\n🙋 %d iscritti\n🔒 Aperto fino alle %s",
+		"checkin.qr":                "Scansiona per fare il check-in a %s",
+		"checkin.closed":            "Il check-in è stato chiuso",
+		"checkin.usage":             "Invia <code>/checkin</code> seguito dal codice mostrato dall'organizzatore per essere segnato presente",
+		"checkin.done":              "Check-in fatto per <b>%s</b> - %s",
+		"btn.roster":                "Presenze",
+		"btn.close_checkin":         "Chiudi check-in",
+		"btn.checkin":               "Check-in",
+		"stats.title":               "📊 <b>Statistiche di %s</b>\n",
+		"template.title":            "⧉ <b>Modelli di %s</b>\n",
+		"template.empty":            "\n<i>Ancora nessun modello, salvane uno dal pulsante ⧉ di un evento nella vista del giorno di /publish</i>",
+		"template.help":             "\n\nTocca un modello per aggiungerlo ai giorni che selezioni nel calendario, usa <code>/template rename</code> seguito dal suo numero e dal nuovo nome per rinominarlo",
+		"template.timed":            "Evento alle %s",
+		"template.all_day":          "Evento di giornata intera|Evento di giornata intera di %d giorni",
+		"copy.select":               "Seleziona i giorni in cui aggiungere <b>%s</b>: %s",
+		"select.title":              "Tocca i giorni da aggiungere, tutti allo stesso orario: %[2]s\n<i>%[1]d giorno selezionato</i>|Tocca i giorni da aggiungere, tutti allo stesso orario: %[2]s\n<i>%[1]d giorni selezionati</i>",
+		"select.hour":               "Seleziona l'ora del giorno selezionato|Seleziona l'ora dei %d giorni selezionati",
+		"select.minute":             "Seleziona i minuti dopo le %s",
+		"select.review":             "<b>Aggiunta del giorno selezionato alle %[2]s:</b>\n|<b>Aggiunta dei %[1]d giorni selezionati alle %[2]s:</b>\n",
+		"select.existing":           "<i>(già nel calendario)</i>",
+		"select.added":              "<b>%d data aggiunta</b>\n|<b>%d date aggiunte</b>\n",
+		"select.already":            "\n\n<b>%d data era già nel calendario:</b>|\n\n<b>%d date erano già nel calendario:</b>",
+		"select.passed":             "\n\n<b>%d data saltata perché già passata:</b>|\n\n<b>%d date saltate perché già passate:</b>",
+		"stats.events":              "\n📅 %d eventi in programma e %d passati",
+		"stats.joins":               "\n📈 %d iscrizioni nelle ultime %d settimane",
+		"stats.attendance":          "\n👥 %.1f partecipanti in media per evento passato",
+		"stats.no_shows":            "\n🙈 %.0[2]f%% di assenti nell'evento con check-in|\n🙈 %.0[2]f%% di assenti nei %[1]d eventi con check-in",
+		"stats.repeat":              "\n🔁 %d partecipanti su %d si sono iscritti a più di un evento",
+		"stats.conversion":          "\n🔗 Inviti aperti %d volte da %d utenti, %d di loro si sono iscritti (%.0f%%)",
+		"stats.popular":             "\n🏆 Più richiesto: %s alle %02d:00",
+		"stats.chart.joins":         "📈 Iscrizioni per settimana a %s",
+		"stats.chart.weekdays":      "📅 Partecipanti per giorno della settimana degli eventi di %s",
+		"stats.chart.hours":         "🕒 Partecipanti per ora di inizio degli eventi di %s",
+		"btn.ratings":               "Voti",
+		"link.calendar_feed":        "\n\n📅 Iscriviti al tuo calendario dalle app calendario di Google, Apple o Thunderbird:\n<code>%s</code>",
+		"link.attendee_feed":        "\n\n🙋 Iscriviti agli eventi a cui partecipi:\n<code>%s</code>\n<i>Tieni privati questi link</i>",
+		"inline.dates":              "%d data in arrivo|%d date in arrivo",
+		"card.calendar":             "📅 <b>%s</b>\n%s\n\n",
+		"event.show":                "🎟 <b>%s</b>\n%s\n\n🕒 %s\n👥 %s",
+		"event.seats":               "\n🪑 %d posto libero|\n🪑 %d posti liberi",
+		"card.no_dates":             "<i>Non ci sono date in arrivo</i>",
+		"card.pick_date":            "<i>Tocca una data per partecipare</i>",
+		"card.expired":              "⌛️ Questo evento non è più disponibile",
+		"feed.attendee_name":        "Eventi a cui partecipi",
+		"feed.attendee_description": "Eventi a cui partecipi con CalenDaggerbill",
+		"language.select":           "Seleziona la lingua che preferisci",
+		"token.show": "<b>Token API</b>\n" +
+			"Usalo per gestire il tuo calendario dai tuoi strumenti tramite l'API HTTP, " +
+			"la sua descrizione è disponibile su <code>/api/openapi.json</code>\n" +
+			"Generare un nuovo token invalida il precedente",
+		"token.disabled": "\n\n⚠️ L'API HTTP non è attiva su questo bot",
+		"token.issued": "🔑 Il tuo nuovo token API è:\n<code>%s</code>\n\n" +
+			"<i>Tienilo segreto, dà pieno accesso al tuo calendario. Non verrà mostrato di nuovo</i>",
+		"webhook.title": "<b>Webhook</b>\n" +
+			"Gli eventi del tuo calendario (partecipazioni, abbandoni, date aggiunte o rimosse, modifiche e promemoria) vengono inviati in JSON a questi URL.\n" +
+			"Invia <code>/webhook add https://example.com/hook</code> per registrarne uno nuovo\n",
+		"webhook.empty": "\n<i>Nessun webhook registrato</i>",
+		"webhook.help":  "Usa <code>/webhook add</code> seguito dall'URL del webhook",
+		"webhook.added": "<b>Webhook <code>%s</code> registrato</b>\n%s\n\n" +
+			"Segreto usato per firmare gli eventi: <code>%s</code>\n" +
+			"<i>L'header X-Calendaggerbill-Signature contiene l'HMAC-SHA256 del corpo usando il segreto</i>",
+		"webhook.log":       "<b>Ultimi invii</b>\n",
+		"webhook.log_empty": "\n<i>Nessun invio</i>",
+		"webhook.delivery":  "%s <code>%s</code> ➡️ %s (tentativo %d, stato %d)",
+		"caldav.show": "<b>CalDAV</b>\n" +
+			"Aggiungi un account con queste impostazioni alla tua app calendario per vedere e modificare i tuoi eventi da lì\n" +
+			"\n🌐server: <code>%s</code>" +
+			"\n👤nome utente: <code>%d</code>",
+		"caldav.password": "\n🔑password: <code>%s</code>\n\n" +
+			"<i>Tienila segreta, non verrà mostrata di nuovo</i>",
+		"caldav.disabled": "CalDAV non è attivo su questo bot",
+		"status.show": "<b>Stato del bot</b>\n" +
+			"\n📅calendari: %d" +
+			"\n🎟eventi in arrivo: %d" +
+			"\n👤utenti conosciuti: %d" +
+			"\n💾archiviazione: <code>%s</code>" +
+			"\n🕒fuso orario: <code>%s</code>",
+		"settings.show": "<b>Le tue preferenze</b>\n" +
+			"\n🌐lingua: <code>%s</code>" +
+			"\n📆la settimana inizia di: <code>%s</code>" +
+			"\n#️⃣numeri delle settimane: <code>%v</code>",
+	},
+}
@@ -2,29 +2,85 @@ package main
 
 import (
 	"fmt"
+	"html"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/DazFather/parrbot/message"
 	"github.com/DazFather/parrbot/robot"
 	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
 )
 
 func main() {
+	var err error
+	if config, err = LoadConfiguration(os.Args[1:]); err != nil {
+		log.Fatal("Config error: ", err)
+	}
+	if err = robot.Config.SetAPIToken(config.Token); err != nil {
+		log.Fatal("Config error: ", err)
+	}
+	if err = LoadData(config.Storage); err != nil {
+		log.Fatal("Storage error: ", err)
+	}
+
+	// Start saving data job and save it one last time before exiting
+	if config.Storage != "" {
+		Repeat(SAVE_INTERVAL, saveData)
+		go func() {
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+			<-stop
+			saveData()
+			os.Exit(0)
+		}()
+	}
+	// Start the HTTP API if enabled
+	StartServer()
 	// Start cleaning unused calendars job
-	go Repeat(DEFAULT_UNUSED_TIME, UnusedCalendarsRemover(DEFAULT_UNUSED_TIME))
+	Repeat(time.Duration(config.CleanupInterval), UnusedCalendarsRemover(time.Duration(config.UnusedAfter)))
+	if config.ArchiveRetention > 0 {
+		Repeat(time.Duration(config.CleanupInterval), ExpiredArchiveRemover(time.Duration(config.ArchiveRetention)))
+	}
 	// Start the bot with the following commands:
-	robot.Start(
-		startHandler,   // start menu & handle join link
-		joinHandler,    // confirm join
-		publishHandler, // create a new calendar
-		closeHandler,   // close any menu and show toast alert
-		alertHandler,   // show toast alert
-		editHandler,    // edit calendar menu
-		setHandler,     // confirm edit calendar menu
-		linkHandler,    // show shareable link
-	)
+	robot.LoadCommands(commands)
+	log.Println(Poll())
 }
 
+// commands are all the commands handled by the bot
+var commands = guard(
+	startHandler,        // start menu & handle join link
+	joinHandler,         // confirm join
+	publishHandler,      // create a new calendar
+	closeHandler,        // close any menu and show toast alert
+	alertHandler,        // show toast alert
+	editHandler,         // edit calendar menu
+	linkHandler,         // show shareable link
+	languageHandler,     // choose the language of the bot
+	settingsHandler,     // change user's preferences
+	statusHandler,       // show bot status to admins
+	tokenHandler,        // manage the API token
+	webhookHandler,      // manage the webhooks of the calendar
+	caldavHandler,       // manage the CalDAV credentials
+	inlineHandler,       // share calendars and events in any chat
+	rateHandler,         // rate an event that occurred
+	feedbackHandler,     // show the ratings of the past events
+	historyHandler,      // show the events that occurred
+	checkInHandler,      // mark the attendees present
+	statsHandler,        // show the statistics of the calendar
+	templateHandler,     // manage the templates of the events
+	changesHandler,      // show and undo the changes of the calendar
+	conversationHandler, // answer, go back or cancel the conversations
+	cancelHandler,       // cancel the current conversation
+	answerHandler,       // receive the answers to the conversations
+)
+
 /* --- BOT COMMAND --- */
 
 var startHandler = robot.Command{
@@ -32,7 +88,10 @@ var startHandler = robot.Command{
 	Trigger:     "/start",
 	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
-		var payload = extractPayload(update)
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
 		if len(payload) == 0 {
 			var (
 				now  string = string(Today())
@@ -41,42 +100,97 @@ var startHandler = robot.Command{
 			)
 
 			if calendar := CalendarOf(bot.ChatID); calendar != nil {
-				text = fmt.Sprint(LOGO, " <i>Hi! What can I do for you today?</i>\n",
-					"Here is some infos about your calendar:",
-					"\n", NOTIF_ON, "notification: <code>", calendar.notification, "</code>",
-					"\n🎟incoming events: ", len(calendar.dates),
-					"\n", PEOPLE, "people reached: ", len(calendar.AllCurrentAttendee()),
-					"\n🏷name: <code>", calendar.name, "</code>",
-					"\n📑description: <code>", calendar.description, "</code>",
-				)
+				text = fmt.Sprint(LOGO, " ", lang.T("start.organizer",
+					NOTIF_ON, calendar.notification,
+					len(calendar.dates),
+					calendar.Capacity(lang),
+					PEOPLE, len(calendar.AllCurrentAttendee()),
+					calendar.name,
+					calendar.description,
+				))
 
 				tgui.InlineKbdOpt(opts, [][]tgui.InlineButton{
-					{tgui.InlineCaller("➕ Add events", "/publish", now)},
-					{tgui.InlineCaller("📝 Edit calendar", "/edit")},
-					{tgui.InlineCaller("📨 Invite users", "/link")},
+					{
+						tgui.InlineCaller(lang.T("btn.add_events"), "/publish", now),
+						tgui.InlineCaller(lang.T("btn.templates"), "/template"),
+					},
+					{
+						tgui.InlineCaller("📆 "+lang.T("view.week"), "/publish", now, WEEK_VIEW),
+						tgui.InlineCaller("📋 "+lang.T("view.agenda"), "/publish", now, AGENDA_VIEW),
+					},
+					{
+						tgui.InlineCaller(lang.T("btn.edit_calendar"), "/edit"),
+						tgui.InlineCaller("📜 "+lang.T("btn.changes"), "/changes"),
+					},
+					{tgui.InlineCaller(lang.T("btn.invite_users"), "/link")},
+					{tgui.InlineCaller(lang.T("btn.stats"), "/stats")},
+					{tgui.InlineCaller(SETTINGS.Text(lang.T("btn.settings")), "/settings")},
 				})
 			} else {
-				text = fmt.Sprint("👋 <b>Welcome, I'm Calen-Daggerbill!</b> ", LOGO, "\n",
-					"<i>Your <a href=\"https://github.com/DazFather/calendaggerbill\">open source</a>",
-					" robo-hummingbird that will assist you to mange your calendar</i>",
-					"\n\nUsing me is very easy and free:",
-					"\n First of all you need to create a calendar, ",
-					"<i>use the button below or the command </i> /publish",
-				)
+				text = lang.T("start.welcome", LOGO)
 
-				tgui.InlineKbdOpt(opts, [][]tgui.InlineButton{{
-					tgui.InlineCaller("🆕 Create new calendar", "/publish", now),
-				}})
+				tgui.InlineKbdOpt(opts, [][]tgui.InlineButton{
+					{tgui.InlineCaller(lang.T("btn.create_calendar"), "/publish", now)},
+					{tgui.InlineCaller(SETTINGS.Text(lang.T("btn.settings")), "/settings")},
+				})
 				tgui.DisableWebPagePreview(opts)
 			}
-			tgui.ShowMessage(*update, text, opts)
+			showMessage(update, text, opts)
 			return nil
 		}
 
-		if calendar := retreiveCalendar(payload[0]); calendar != nil {
-			return buildDateListMessage(*calendar, bot.ChatID)
+		if key, code, ok := DecodeCheckInLink(payload[0]); ok {
+			calendar, date, err := CheckInEvent(bot.ChatID, key, code)
+			if err != nil {
+				return buildErrorMessage(lang, lang.Error(err))
+			}
+			return buildCheckInDone(lang, *calendar, date)
+		}
+
+		if invitation, date, ok := DecodeEventLink(payload[0]); ok {
+			if calendar := retreiveCalendar(invitation); calendar != nil {
+				if update.Message != nil {
+					VisitCalendar(calendar, bot.ChatID)
+				}
+				return buildEventMessage(lang, *calendar, date, bot.ChatID)
+			}
+			return buildErrorMessage(lang, lang.T("error.invalid_link"))
+		}
+
+		var calendar = retreiveCalendar(payload[0])
+		if calendar == nil {
+			return buildErrorMessage(lang, lang.T("error.invalid_link"))
 		}
-		return buildErrorMessage("Invalid invitation link")
+		if len(payload) < 3 {
+			if update.Message != nil {
+				VisitCalendar(calendar, bot.ChatID)
+			}
+			return buildDateListMessage(lang, *calendar, bot.ChatID, 0, "")
+		}
+		if callback := update.CallbackQuery; callback != nil {
+			telegram.Delete(callback.Message)
+		}
+		if payload[1] == "list" {
+			var page, _ = strconv.Atoi(payload[2])
+			if len(payload) < 4 {
+				return buildDateListMessage(lang, *calendar, bot.ChatID, page, "")
+			}
+			return buildDateListMessage(lang, *calendar, bot.ChatID, page, ParseDateFilter(payload[3]))
+		}
+
+		var date, err = ParseDate(payload[2])
+		if err != nil {
+			return buildErrorMessage(lang, lang.T("error.invalid_date", err))
+		}
+
+		var v = inviteeViewer(lang, calendar.invitation, bot.ChatID)
+		switch payload[1] {
+		case WEEK_VIEW:
+			return buildWeekMessage(*calendar, date, v)
+		case AGENDA_VIEW:
+			return buildAgendaMessage(*calendar, date, v)
+		}
+		return buildDateListMessage(lang, *calendar, bot.ChatID, 0, "")
 	},
 }
 
@@ -84,18 +198,72 @@ var joinHandler = robot.Command{
 	Trigger: "/join",
 	ReplyAt: message.CALLBACK_QUERY,
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
-		var calendar *Calendar
+		var (
+			calendar *Calendar
+			lang     = extractLanguage(bot, update)
+		)
 
-		if payload := extractPayload(update); len(payload) != 2 {
-			return buildErrorMessage("Invalid joining: " + update.CallbackQuery.Data)
-		} else if c, err := JoinEvent(*update.CallbackQuery.From, payload[0], payload[1]); err != nil {
-			return buildErrorMessage(err.Error())
-		} else {
-			calendar = c
+		var payload = extractPayload(update)
+		if callback := update.CallbackQuery; callback.InlineMessageID != "" {
+			// joining from a card sent using the inline mode, answer with toasts only
+			// as the user might have never started the bot
+			if len(payload) == 3 {
+				TrackCard(callback.InlineMessageID, NewCard(payload[2], payload[0], FormattedDate(payload[1])))
+			}
+			if len(payload) < 2 {
+				Notify(callback, BLOCK, lang.T("error.invalid_joining", callback.Data))
+				return nil
+			}
+			// there is no room for a confirmation, the overlapping events are only reported
+			var conflicts []Conflict
+			if calendar = retreiveCalendar(payload[0]); calendar != nil {
+				conflicts = Conflicts(callback.From.ID, calendar, FormattedDate(payload[1]))
+			}
+			if _, err := JoinEvent(*callback.From, payload[0], payload[1], true, callback.From.ID); err != nil {
+				Notify(callback, BLOCK, lang.Error(err))
+			} else if len(conflicts) > 0 {
+				Notify(callback, icon("⚠️"), lang.T("alert.joined_overlapping", conflicts[0].Calendar.name))
+			} else {
+				Notify(callback, DONE, lang.T("alert.joined"))
+			}
+			return nil
 		}
 
-		Collapse(update.CallbackQuery, DONE, "You joined this event")
-		return buildDateListMessage(*calendar, bot.ChatID)
+		if len(payload) != 2 && (len(payload) != 3 || payload[2] != JOIN_ANYWAY) {
+			return buildErrorMessage(lang, lang.T("error.invalid_joining", update.CallbackQuery.Data))
+		}
+		c, err := JoinEvent(*update.CallbackQuery.From, payload[0], payload[1], len(payload) == 3, bot.ChatID)
+		if err == OVERLAPPING {
+			text, kbd := buildConflictMessage(lang, *c, FormattedDate(payload[1]), Conflicts(bot.ChatID, c, FormattedDate(payload[1])))
+			showMessage(update, text, genDefaultEditOpt(kbd...))
+			return nil
+		} else if err != nil {
+			return buildErrorMessage(lang, lang.Error(err))
+		}
+		calendar = c
+
+		Collapse(update.CallbackQuery, DONE, lang.T("alert.joined"))
+		return buildDateListMessage(lang, *calendar, bot.ChatID, 0, "")
+	},
+}
+
+var inlineHandler = robot.Command{
+	ReplyAt: message.INLINE_QUERY + message.CHOSEN_INLINE_RESULT,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		if chosen := update.ChosenInlineResult; chosen != nil {
+			TrackCard(chosen.InlineMessageID, ParseCardID(chosen.ResultID))
+			return nil
+		}
+
+		var (
+			query   = update.InlineQuery
+			results = []echotron.InlineQueryResult{}
+		)
+		if calendar := CalendarOf(bot.ChatID); calendar != nil {
+			results = append(results, buildInlineResults(DetectLanguage(*query.From), *calendar, query.Query)...)
+		}
+		telegram.AnswerInline(query, results, &echotron.InlineQueryOptions{IsPersonal: true})
+		return nil
 	},
 }
 
@@ -104,62 +272,195 @@ var publishHandler = robot.Command{
 	Trigger:     "/publish",
 	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
-		var msg message.Any
+		var (
+			msg  message.Any
+			lang = extractLanguage(bot, update)
+		)
 
 		switch payload := extractPayload(update); len(payload) {
 		case 0:
 			if callback := update.CallbackQuery; callback != nil {
-				msg = buildErrorMessage("No given payload")
+				msg = buildErrorMessage(lang, lang.T("error.no_payload"))
 			} else {
-				msg = buildCalendarMessage(Now(), "🗓 Select a day from the calendar: ")
+				msg = buildCalendarMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, Now())
 			}
 		case 1:
 			var date, err = ParseDate(payload[0])
 			if err != nil {
-				msg = buildErrorMessage("Invaid date: " + err.Error())
+				msg = buildErrorMessage(lang, lang.T("error.invalid_date", err))
 				break
 			}
 
-			msg = buildCalendarMessage(date, "🗓 Select a day from the calendar: ")
+			msg = buildCalendarMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, date)
 		case 2:
 			var date, err = ParseDate(payload[0])
 			if err != nil {
-				msg = buildErrorMessage("Invaid date: " + err.Error())
+				msg = buildErrorMessage(lang, lang.T("error.invalid_date", err))
 				break
 			}
 
-			if payload[1] == "refresh" {
-				msg = buildCalendarMessage(date, "🗓 Select a day from the calendar: ")
+			switch payload[1] {
+			case MONTH_VIEW:
+				msg = buildCalendarMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, date)
+			case WEEK_VIEW, AGENDA_VIEW:
+				var calendar = CalendarOf(bot.ChatID)
+				if calendar == nil {
+					msg = buildCalendarMessage(lang, nil, bot.ChatID, date)
+				} else if payload[1] == WEEK_VIEW {
+					msg = buildWeekMessage(*calendar, date, organizerViewer(lang, bot.ChatID))
+				} else {
+					msg = buildAgendaMessage(*calendar, date, organizerViewer(lang, bot.ChatID))
+				}
+			case "months":
+				msg = buildMonthPickerMessage(lang, date)
+			case RANGE_VIEW:
+				msg = buildRangeMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, date, nil)
+			case SELECT_VIEW, SELECT_DAY, SELECT_CLEAR, HOUR_VIEW, MINUTE_VIEW, BULK_REVIEW:
+				var (
+					text string
+					kbd  [][]tgui.InlineButton
+				)
+				switch payload[1] {
+				case SELECT_DAY:
+					ToggleDay(bot.ChatID, date)
+				case SELECT_CLEAR:
+					clearSelection(bot.ChatID)
+				}
+
+				switch step := payload[1]; {
+				case step == SELECT_VIEW || step == SELECT_DAY || step == SELECT_CLEAR:
+					text, kbd = buildSelectMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, date)
+				case len(selections[bot.ChatID]) == 0:
+					Notify(update.CallbackQuery, BLOCK, lang.T("error.no_selection"))
+					return nil
+				case step == HOUR_VIEW:
+					text, kbd = buildHourMessage(lang, bot.ChatID, date)
+				case step == MINUTE_VIEW:
+					text, kbd = buildMinuteMessage(lang, date)
+				default:
+					text, kbd = buildBulkReview(lang, CalendarOf(bot.ChatID), bot.ChatID, date)
+				}
+				if update.Message != nil {
+					telegram.Delete(update.Message)
+				}
+				showMessage(update, text, genDefaultEditOpt(kbd...))
+				return nil
+			case BULK_ADD:
+				if len(selections[bot.ChatID]) == 0 || update.CallbackQuery == nil {
+					msg = buildErrorMessage(lang, lang.T("error.no_selection"))
+					break
+				}
+
+				var (
+					hasCalendar                       = CalendarOf(bot.ChatID) != nil
+					calendar, added, existing, passed = AddSelectedDays(*update.CallbackQuery.From, date)
+				)
+				if !hasCalendar {
+					return buildCreatedMessage(lang, *calendar)
+				}
+				msg = buildBulkSummary(lang, *calendar, added, existing, passed)
+			case "day":
+				if calendar := CalendarOf(bot.ChatID); calendar != nil {
+					msg = buildDayMessage(lang, *calendar, date)
+				} else {
+					msg = buildCalendarMessage(lang, nil, bot.ChatID, date)
+				}
+			}
+			if msg != nil {
 				break
 			}
 
 			var (
 				hasCalendar bool = CalendarOf(bot.ChatID) != nil
-				link             = GetShareLink(botUsername(), *AddToCalendar(*update.CallbackQuery.From, date))
+				calendar         = AddToCalendar(*update.CallbackQuery.From, date)
 			)
-			Notify(update.CallbackQuery, DONE, fmt.Sprint("Date: ", CALENDAR, " ", date, " added to your calendar "))
+			if overlapping := calendar.Overlapping(date.Formatted()); len(overlapping) > 0 {
+				Notify(update.CallbackQuery, icon("⚠️"), lang.Plural("alert.date_overlapping", len(overlapping), date.Beautify(lang), calendar.Describe(overlapping[0], lang)))
+			} else {
+				Notify(update.CallbackQuery, DONE, lang.T("alert.date_added", CALENDAR, date.Beautify(lang)))
+			}
 			if hasCalendar {
 				break
 			}
-			return genDefaultMessage(
-				DONE,
-				fmt.Sprint(
-					"<b>You calendar has been created</b>\n",
-					"Use the previous message or /publish again to add a new avaiable dates\n",
-					"Send /edit to modify your calendar's settings like name, description and notification\n",
-					"Share the following link to make people join your events: ", link,
-				),
-				[]tgui.InlineButton{
-					tgui.InlineCaller("🔙 Back", "/start"),
-					BTN_CLOSE,
-				},
+			return buildCreatedMessage(lang, *calendar)
+		case 3:
+			if payload[1] == COPY_VIEW || payload[1] == COPY_ADD {
+				var (
+					day, err    = ParseDate(payload[0])
+					calendar    = CalendarOf(bot.ChatID)
+					template    Template
+					hasTemplate bool
+				)
+				if calendar != nil {
+					template, hasTemplate = ResolveTemplate(*calendar, payload[2])
+				}
+				if err != nil || !hasTemplate {
+					msg = buildErrorMessage(lang, lang.T("error.invalid_template"))
+					break
+				}
+
+				if payload[1] == COPY_ADD && update.CallbackQuery != nil {
+					if _, date, added := ApplyTemplate(*update.CallbackQuery.From, template, day); added {
+						Notify(update.CallbackQuery, DONE, lang.T("alert.copied", calendar.Describe(date, lang)))
+					} else {
+						Notify(update.CallbackQuery, BLOCK, lang.T("alert.copy_exists"))
+					}
+				}
+				if update.Message != nil {
+					telegram.Delete(update.Message)
+				}
+				text, kbd := buildCopyMessage(lang, *calendar, bot.ChatID, day, payload[2])
+				showMessage(update, text, genDefaultEditOpt(kbd...))
+				return nil
+			}
+
+			// the month shown and the first day selected, or the first and the last day of the event
+			var first, err = ParseDate(payload[0])
+			var last Date
+			if err == nil {
+				last, err = ParseDate(payload[2])
+			}
+			if err != nil {
+				msg = buildErrorMessage(lang, lang.T("error.invalid_date", err))
+				break
+			}
+
+			if payload[1] == RANGE_VIEW {
+				msg = buildRangeMessage(lang, CalendarOf(bot.ChatID), bot.ChatID, first, &last)
+				break
+			}
+			if payload[1] != SPAN_ADD || update.CallbackQuery == nil {
+				msg = buildErrorMessage(lang, lang.T("error.no_payload"))
+				break
+			}
+			if last.IsBefore(first) {
+				first, last = last, first
+			}
+
+			var (
+				hasCalendar     = CalendarOf(bot.ChatID) != nil
+				calendar, added = AddSpanToCalendar(*update.CallbackQuery.From, first, last)
+				date            = first.DayStart().Formatted()
 			)
+			if !added {
+				Notify(update.CallbackQuery, BLOCK, lang.T("alert.span_exists", lang.Format(first.Time, DAY_FORMAT)))
+				return nil
+			}
+			if overlapping := calendar.Overlapping(date); len(overlapping) > 0 {
+				Notify(update.CallbackQuery, icon("⚠️"), lang.Plural("alert.date_overlapping", len(overlapping), calendar.Describe(date, lang), calendar.Describe(overlapping[0], lang)))
+			} else {
+				Notify(update.CallbackQuery, DONE, lang.T("alert.date_added", CALENDAR, calendar.Describe(date, lang)))
+			}
+			if hasCalendar {
+				break
+			}
+			return buildCreatedMessage(lang, *calendar)
 		}
 
 		if callback := update.CallbackQuery; callback != nil {
-			callback.Delete()
+			telegram.Delete(callback.Message)
 		} else {
-			update.Message.Delete()
+			telegram.Delete(update.Message)
 		}
 
 		return msg
@@ -173,143 +474,727 @@ var editHandler = robot.Command{
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
 		var (
 			calendar         = CalendarOf(bot.ChatID)
-			current          string
-			field, suggested string = extractFieldValue(update)
+			field, suggested = extractFieldValue(update)
+			lang             = extractLanguage(bot, update)
+			text             string
+			kbd              [][]tgui.InlineButton
 		)
 		if update.Message != nil {
-			update.Message.Delete()
+			telegram.Delete(update.Message)
 		}
 		if calendar == nil {
-			return buildErrorMessage("You don't have a calendar yet, use the command /publish to create a new one")
-		}
-
-		if field == "" || suggested == "" {
-			return genDefaultMessage(
-				icon("🆘"),
-				fmt.Sprint(
-					"Use this command to edit your calendar, at the moment you can change name, description and notification\n",
-					"To do so just use the command followed by what you want to edit ",
-					"(<code>name</code>, <code>description</code> or <code>notification</code>)",
-					" and then the new value, ex:\n <code>/edit name My new AMAZING✨ name</code>",
-					"\nFor notification the allowed values are <code>on</code> or <code>off</code> only",
-				),
-				tgui.Wrap(BTN_CANCEL),
-			)
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
 		}
 
-		switch field {
-		case "name":
-			current = calendar.name
-		case "description":
-			current = calendar.description
-		case "notification":
-			if suggested == "toggle" {
-				suggested = calendar.notification.Toggle().String()
-			} else if ParseToggler(suggested) == nil {
-				return buildErrorMessage("Invaild specifier for this command (" + suggested + "), use <code>on</code>, <code>off</code> instead")
+		switch {
+		case field == "":
+			text, kbd = Converse(bot.ChatID, lang, EDIT_FLOW, nil, "field")
+		case suggested == "":
+			if _, err := FieldOf(*calendar, field); err != nil {
+				return buildErrorMessage(lang, lang.T("edit.invalid_field", html.EscapeString(field)))
 			}
-			current = calendar.notification.String()
+			text, kbd = Converse(bot.ChatID, lang, EDIT_FLOW, map[string]string{"field": field}, "field", "value")
 		default:
-			return buildErrorMessage("Invaild specifier for this command: \"<i>" + field + "</i>\", use <code>name</code> or <code>description</code> instead")
-		}
-
-		tgui.ShowMessage(*update,
-			fmt.Sprint("📝 Your calendar's ", field, " will change\n",
-				"<i>from:</i> <code>", current, "</code>\n",
-				"<i>to:</i> <code>", suggested, "</code>\n",
-				"\n<b>Confirm the change?</b>",
-			),
-			genDefaultEditOpt([]tgui.InlineButton{
-				tgui.InlineCaller(CONFIRM.Text("Confirm"), "/set", field, suggested),
-				BTN_CANCEL,
-			}),
+			if field == "notification" && suggested == "toggle" {
+				// toggle a copy, the calendar changes only once confirmed
+				notification := calendar.notification
+				suggested = notification.Toggle().String()
+			}
+			if _, err := buildEditMessage(lang, *calendar, field, suggested); err != nil {
+				return buildErrorMessage(lang, err.Error())
+			}
+			text, kbd = Converse(bot.ChatID, lang, EDIT_FLOW, map[string]string{"field": field, "value": suggested}, "field", "value", "confirm")
+		}
+
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var linkHandler = robot.Command{
+	Description: "Get the links to share and subscribe to calendars",
+	Trigger:     "/link",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
 		)
+		if calendar == nil && !FeedsEnabled() {
+			err := lang.T("error.no_calendar")
+			if update.CallbackQuery == nil {
+				telegram.Delete(update.Message)
+				return buildErrorMessage(lang, err)
+			}
+			Notify(update.CallbackQuery, BLOCK, err)
+			return nil
+		}
+
+		if payload := extractPayload(update); len(payload) > 0 && calendar != nil {
+			var showQR = payload[0] == "qr"
+			if showQR {
+				payload = payload[1:]
+			}
+
+			var (
+				link    = GetShareLink(telegram.Username(), *calendar)
+				caption = calendar.name
+				date    FormattedDate
+			)
+			if len(payload) > 0 {
+				if date = FormattedDate(payload[0]); calendar.dates[date] == nil {
+					Notify(update.CallbackQuery, BLOCK, lang.T("error.invalid_event"))
+					return nil
+				}
+				link, caption = GetEventLink(telegram.Username(), *calendar, date), calendar.name+" - "+calendar.Describe(date, lang)
+			}
+
+			if showQR {
+				if callback := update.CallbackQuery; callback != nil {
+					telegram.Answer(callback, nil)
+				}
+				return buildQRCodeMessage(lang, link, caption)
+			}
+			showMessage(update, lang.T("link.event", calendar.Describe(date, lang), link), genDefaultEditOpt(
+				tgui.Wrap(tgui.InlineCaller(lang.T("btn.qr_code"), "/link", "qr", string(date))),
+				[]tgui.InlineButton{backButton(lang, "/publish", string(date), "day"), closeButton(lang)},
+			))
+			return nil
+		}
+
+		var text string
+		if calendar != nil {
+			text = lang.T("link.show", GetShareLink(telegram.Username(), *calendar))
+			if FeedsEnabled() {
+				text += lang.T("link.calendar_feed", CalendarFeed(calendar))
+			}
+		}
+		if FeedsEnabled() {
+			text += lang.T("link.attendee_feed", AttendeeFeed(bot.ChatID))
+		}
+
+		var kbd [][]tgui.InlineButton
+		if calendar != nil {
+			kbd = append(kbd, []tgui.InlineButton{
+				{Text: lang.T("btn.share_inline"), SwitchInlineQuery: calendar.name},
+				tgui.InlineCaller(lang.T("btn.qr_code"), "/link", "qr"),
+			})
+		}
+		showMessage(update, strings.TrimSpace(text), genDefaultEditOpt(append(kbd, []tgui.InlineButton{
+			backButton(lang, "/start"),
+			closeButton(lang),
+		})...))
 		return nil
 	},
 }
 
-var setHandler = robot.Command{
-	Trigger: "/set",
+var rateHandler = robot.Command{
+	Trigger: "/rate",
 	ReplyAt: message.CALLBACK_QUERY,
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
 		var (
-			callback     *message.CallbackQuery = update.CallbackQuery
-			field, value string                 = extractFieldValue(update)
-			previous     string
-			calendar     *Calendar = CalendarOf(bot.ChatID)
-			needWarning  bool
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
+		if len(payload) != 3 {
+			Notify(update.CallbackQuery, BLOCK, lang.T("error.no_payload"))
+			return nil
+		}
+
+		var rating, _ = strconv.Atoi(payload[2])
+		if _, err := RateEvent(bot.ChatID, payload[0], FormattedDate(payload[1]), rating); err != nil {
+			Notify(update.CallbackQuery, BLOCK, lang.Error(err))
+			return nil
+		}
+
+		text, kbd := Converse(bot.ChatID, lang, COMMENT_FLOW, map[string]string{
+			"invitation": payload[0],
+			"date":       payload[1],
+			"rating":     payload[2],
+		}, "comment")
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var feedbackHandler = robot.Command{
+	Description: "See the ratings of your past events",
+	Trigger:     "/feedback",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
 		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
 		if calendar == nil {
-			Collapse(callback, BLOCK, "Unable to set: no calendar found")
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		if payload := extractPayload(update); len(payload) == 1 {
+			var date = FormattedDate(payload[0])
+			if calendar.archive[date] == nil {
+				Notify(update.CallbackQuery, BLOCK, lang.T("error.invalid_event"))
+				return nil
+			}
+			showMessage(update, buildFeedbackDetail(lang, *calendar, date), genDefaultEditOpt([]tgui.InlineButton{
+				backButton(lang, "/feedback"),
+				closeButton(lang),
+			}))
 			return nil
 		}
-		if field == "" && value == "" {
-			Collapse(callback, BLOCK, "Unable to set: invalid command")
+
+		text, kbd := buildFeedbackSummary(lang, *calendar)
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var historyHandler = robot.Command{
+	Description: "See who attended your past events",
+	Trigger:     "/history",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
+			payload  = extractPayload(update)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+		if calendar == nil {
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		if len(payload) == 2 {
+			var date = FormattedDate(payload[0])
+			if calendar.archive[date] == nil {
+				Notify(update.CallbackQuery, BLOCK, lang.T("error.invalid_event"))
+				return nil
+			}
+			var row = []tgui.InlineButton{backButton(lang, "/history", payload[1])}
+			if _, count := calendar.archive[date].Rating(); count > 0 {
+				row = append(row, tgui.InlineCaller("⭐ "+lang.T("btn.ratings"), "/feedback", string(date)))
+			}
+			if CanCheckIn(*calendar, date) {
+				row = append(row, tgui.InlineCaller("🎫 "+lang.T("btn.checkin"), "/checkin", "show", string(date)))
+			}
+			showMessage(update, buildRoster(lang, *calendar, date), genDefaultEditOpt(row, tgui.Wrap(closeButton(lang))))
 			return nil
 		}
 
-		switch field {
-		case "notification":
-			previous = calendar.notification.String()
-			calendar.notification = *ParseToggler(value)
-		case "name":
-			previous = calendar.name
-			calendar.name = value
-			needWarning = true
-		case "description":
-			previous = calendar.description
-			calendar.description = value
-			needWarning = true
-		default:
-			Collapse(callback, BLOCK, "Unable to set: invalid field")
+		var page int
+		if len(payload) == 1 {
+			page, _ = strconv.Atoi(payload[0])
+		}
+		text, kbd := buildHistoryMessage(lang, *calendar, page)
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var changesHandler = robot.Command{
+	Description: "See and undo the changes of your calendar",
+	Trigger:     "/changes",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
+			payload  = extractPayload(update)
+			page     string
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
 		}
+		if calendar == nil {
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		if len(payload) == 3 {
+			id, _ := strconv.Atoi(payload[1])
+			change := calendar.change(id)
+			if change == nil || !calendar.Reversible(*change) {
+				Notify(update.CallbackQuery, BLOCK, lang.Error(NOT_REVERSIBLE))
+				return nil
+			}
 
-		text := "<b>Your calendar has been edited</b>\nCalendar's " + field + " successfully changed to:\n " + value
-		if needWarning {
-			attendee := calendar.AllCurrentAttendee()
-			for _, userID := range attendee {
-				genDefaultMessage(icon("❕"), fmt.Sprint(
-					"The ", field, " of a calendar that you have joined changed:\n<i>", previous, "</i> ➡️ <b>", value, "</b>",
-				)).Send(int64(userID))
+			if page = payload[2]; payload[0] == "undo" {
+				text, kbd := buildUndoMessage(lang, *calendar, bot.ChatID, *change, page)
+				showMessage(update, text, genDefaultEditOpt(kbd...))
+				return nil
+			}
+			warned, err := UndoChange(calendar, id, bot.ChatID)
+			if err != nil {
+				Notify(update.CallbackQuery, BLOCK, lang.Error(err))
+				return nil
 			}
-			if tot := len(attendee); tot > 0 {
-				text += fmt.Sprint(", all ", tot, " attendee has been warned")
+			var alert = lang.T("alert.undone")
+			if warned > 0 {
+				alert += lang.Plural("set.warned", warned)
 			}
+			Notify(update.CallbackQuery, DONE, alert)
+		} else if len(payload) == 1 {
+			page = payload[0]
 		}
 
-		tgui.ShowMessage(*update, DONE.Text(text), genDefaultEditOpt([]tgui.InlineButton{
-			tgui.InlineCaller("↩️ Turn "+field+" back to "+previous, "/edit", field, previous),
-			BTN_CLOSE,
-		}))
+		var index, _ = strconv.Atoi(page)
+		text, kbd := buildChangesMessage(lang, *calendar, bot.ChatID, index)
+		showMessage(update, text, genDefaultEditOpt(kbd...))
 		return nil
 	},
 }
 
-var linkHandler = robot.Command{
-	Description: "Get the shareable link to your calendar",
-	Trigger:     "/link",
+var checkInHandler = robot.Command{
+	Description: "Check in an event or open its check-in",
+	Trigger:     "/checkin",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
+			payload  = extractPayload(update)
+		)
+
+		switch {
+		case len(payload) == 1:
+			calendar, date, err := CheckInEvent(bot.ChatID, "", payload[0])
+			if err != nil {
+				return buildErrorMessage(lang, lang.Error(err))
+			}
+			return buildCheckInDone(lang, *calendar, date)
+
+		case len(payload) == 0 && calendar == nil:
+			return genDefaultMessage(icon("🎫"), lang.T("checkin.usage"), tgui.Wrap(closeButton(lang)))
+
+		case len(payload) == 0:
+			if update.Message != nil {
+				telegram.Delete(update.Message)
+			}
+			text, kbd := buildCheckInList(lang, *calendar)
+			showMessage(update, text, genDefaultEditOpt(kbd...))
+			return nil
+
+		case len(payload) != 2 || calendar == nil || update.CallbackQuery == nil:
+			return buildErrorMessage(lang, lang.T("error.no_payload"))
+		}
+
+		var date = FormattedDate(payload[1])
+		switch payload[0] {
+		case "show":
+			open, err := OpenCheckIn(calendar, date)
+			if err != nil {
+				Notify(update.CallbackQuery, BLOCK, lang.Error(err))
+				return nil
+			}
+			text, kbd := buildCheckInMessage(lang, *calendar, date, *open)
+			showMessage(update, text, genDefaultEditOpt(kbd...))
+
+		case "qr":
+			open := CheckInOf(*calendar, date)
+			if open == nil {
+				Notify(update.CallbackQuery, BLOCK, lang.T("error.checkin_closed"))
+				return nil
+			}
+			telegram.Answer(update.CallbackQuery, nil)
+			link := GetCheckInLink(telegram.Username(), *calendar, date, open.Code(clock.Now()))
+			return buildQRCodeMessage(lang, link, lang.T("checkin.qr", calendar.Describe(date, lang)))
+
+		case "roster":
+			if calendar.event(date) == nil {
+				Notify(update.CallbackQuery, BLOCK, lang.T("error.invalid_event"))
+				return nil
+			}
+			showMessage(update, buildRoster(lang, *calendar, date), genDefaultEditOpt(
+				[]tgui.InlineButton{backButton(lang, "/checkin", "show", string(date)), closeButton(lang)},
+			))
+
+		case "close":
+			CloseCheckIn(calendar, date)
+			Notify(update.CallbackQuery, DONE, lang.T("checkin.closed"))
+			text, kbd := buildCheckInList(lang, *calendar)
+			telegram.Edit(update.CallbackQuery, text, genDefaultEditOpt(kbd...))
+
+		default:
+			Notify(update.CallbackQuery, BLOCK, lang.T("error.no_payload"))
+		}
+		return nil
+	},
+}
+
+var statsHandler = robot.Command{
+	Description: "See the statistics of your calendar",
+	Trigger:     "/stats",
 	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
 	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
-		var calendar = CalendarOf(bot.ChatID)
+		var (
+			calendar = CalendarOf(bot.ChatID)
+			lang     = extractLanguage(bot, update)
+			first    = WeekStartOf(bot.ChatID)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
 		if calendar == nil {
-			err := "You don't have a calendar yet, use the command /publish to create a new one"
-			if update.CallbackQuery == nil {
-				update.Message.Delete()
-				return buildErrorMessage(err)
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		var stats = ComputeStats(*calendar, first)
+		if payload := extractPayload(update); len(payload) == 1 {
+			if callback := update.CallbackQuery; callback != nil {
+				telegram.Answer(callback, nil)
 			}
-			Notify(update.CallbackQuery, BLOCK, err)
+			return buildStatsChart(lang, *calendar, stats, payload[0], first)
+		}
+
+		text, kbd := buildStatsMessage(lang, *calendar, stats)
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var templateHandler = robot.Command{
+	Description: "Manage the templates of your events",
+	Trigger:     "/template",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload  = extractPayload(update)
+			lang     = extractLanguage(bot, update)
+			calendar = CalendarOf(bot.ChatID)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+		if calendar == nil {
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		switch append(payload, "")[0] {
+		case "save":
+			template, ok := Template{}, false
+			if len(payload) == 2 {
+				template, ok = TemplateOf(*calendar, FormattedDate(payload[1]))
+			}
+			if !ok {
+				return buildErrorMessage(lang, lang.T("error.invalid_event"))
+			}
+			index, err := calendar.saveTemplate(template)
+			if err != nil {
+				return buildErrorMessage(lang, lang.Error(err))
+			}
+			if callback := update.CallbackQuery; callback != nil {
+				Notify(callback, DONE, lang.T("alert.template_saved", calendar.templates[index].Label(lang)))
+				return nil
+			}
+		case "delete":
+			if len(payload) == 2 {
+				if index, err := strconv.Atoi(payload[1]); err == nil && calendar.removeTemplate(index) {
+					Notify(update.CallbackQuery, DONE, lang.T("alert.template_removed"))
+				}
+			}
+		case "rename":
+			var index, err = strconv.Atoi(append(payload, "", "")[1])
+			if err == nil {
+				err = calendar.renameTemplate(index-1, strings.Join(payload[2:], " "))
+			}
+			if err != nil {
+				return buildErrorMessage(lang, lang.Error(err))
+			}
+		}
+
+		text, kbd := buildTemplatesMessage(lang, *calendar)
+		showMessage(update, text, genDefaultEditOpt(kbd...))
+		return nil
+	},
+}
+
+var languageHandler = robot.Command{
+	Description: "Change the language of the bot",
+	Trigger:     "/language",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+
+		if len(payload) > 0 {
+			lang = ParseLanguage(payload[0])
+			SetLanguage(bot.ChatID, lang)
+			Notify(update.CallbackQuery, DONE, lang.T("alert.language_set", lang.Name()))
+		}
+
+		showMessage(update, LANGUAGE.Text(lang.T("language.select")), genDefaultEditOpt(genLanguageKeyboard(lang)...))
+		return nil
+	},
+}
+
+var settingsHandler = robot.Command{
+	Description: "Change your preferences",
+	Trigger:     "/settings",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
 		}
 
-		tgui.ShowMessage(*update, "Your link: "+GetShareLink(botUsername(), *calendar), genDefaultEditOpt([]tgui.InlineButton{
-			tgui.InlineCaller("🔙 Back", "/start"),
-			BTN_CLOSE,
-		}))
+		switch {
+		case len(payload) == 2 && payload[0] == "weekstart":
+			if day, err := strconv.Atoi(payload[1]); err == nil && day >= 0 && day < 7 {
+				SetWeekStart(bot.ChatID, time.Weekday(day))
+			}
+		case len(payload) == 1 && payload[0] == "weeknumbers":
+			ToggleWeekNumbers(bot.ChatID)
+		}
+
+		showMessage(update,
+			SETTINGS.Text(lang.T("settings.show", lang.Name(), lang.Weekday(WeekStartOf(bot.ChatID)), toggler(ShowWeekNumbers(bot.ChatID)))),
+			genDefaultEditOpt(genSettingsKeyboard(lang, bot.ChatID)...),
+		)
+		return nil
+	},
+}
+
+var tokenHandler = robot.Command{
+	Description: "Get a token to use the HTTP API",
+	Trigger:     "/token",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+			text    = lang.T("token.show")
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+		if config.HTTPAddress == "" {
+			text += lang.T("token.disabled")
+		}
+
+		if len(payload) > 0 {
+			switch payload[0] {
+			case "new":
+				token, err := IssueToken(bot.ChatID)
+				if err != nil {
+					Collapse(update.CallbackQuery, BLOCK, err.Error())
+					return nil
+				}
+				text = lang.T("token.issued", token)
+			case "revoke":
+				if RevokeToken(bot.ChatID) {
+					Notify(update.CallbackQuery, DONE, lang.T("alert.token_revoked"))
+				} else {
+					Notify(update.CallbackQuery, BLOCK, lang.T("alert.no_token"))
+				}
+			}
+		}
+
+		showMessage(update, text, genDefaultEditOpt(
+			[]tgui.InlineButton{
+				tgui.InlineCaller(lang.T("btn.new_token"), "/token", "new"),
+				tgui.InlineCaller(lang.T("btn.revoke_token"), "/token", "revoke"),
+			},
+			[]tgui.InlineButton{closeButton(lang)},
+		))
+		return nil
+	},
+}
+
+var webhookHandler = robot.Command{
+	Description: "Send your calendar's events to other services",
+	Trigger:     "/webhook",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+		if CalendarOf(bot.ChatID) == nil {
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+
+		switch append(payload, "")[0] {
+		case "add":
+			if len(payload) != 2 {
+				return buildErrorMessage(lang, lang.T("webhook.help"))
+			}
+			hook, err := AddWebhook(bot.ChatID, payload[1])
+			if err != nil {
+				return buildErrorMessage(lang, lang.Error(err))
+			}
+			return genDefaultMessage(DONE, lang.T("webhook.added", hook.ID, html.EscapeString(hook.URL), hook.Secret), []tgui.InlineButton{
+				tgui.InlineCaller(lang.T("btn.webhooks"), "/webhook"),
+				closeButton(lang),
+			})
+		case "remove":
+			if len(payload) == 2 && RemoveWebhook(bot.ChatID, payload[1]) {
+				Notify(update.CallbackQuery, DONE, lang.T("alert.webhook_removed"))
+			}
+		case "test":
+			if len(payload) == 2 {
+				if hook := WebhookOf(bot.ChatID, payload[1]); hook != nil {
+					EmitTo(bot.ChatID, *hook, CalendarOf(bot.ChatID).invitation, HOOK_TEST, map[string]interface{}{"webhook": hook.ID})
+					Notify(update.CallbackQuery, DONE, lang.T("alert.webhook_tested"))
+				}
+			}
+		case "log":
+			showMessage(update, buildDeliveryLog(lang, DeliveriesOf(bot.ChatID)), genDefaultEditOpt([]tgui.InlineButton{
+				backButton(lang, "/webhook"),
+				tgui.InlineCaller(REFRESH.Text(lang.T("btn.refresh")), "/webhook", "log"),
+			}))
+			return nil
+		}
+
+		showMessage(update, buildWebhooksText(lang, bot.ChatID), genDefaultEditOpt(genWebhooksKeyboard(lang, bot.ChatID)...))
+		return nil
+	},
+}
+
+var caldavHandler = robot.Command{
+	Description: "Sync your calendar with desktop and mobile apps",
+	Trigger:     "/caldav",
+	ReplyAt:     message.MESSAGE + message.CALLBACK_QUERY,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		var (
+			payload = extractPayload(update)
+			lang    = extractLanguage(bot, update)
+		)
+		if update.Message != nil {
+			telegram.Delete(update.Message)
+		}
+		if CalendarOf(bot.ChatID) == nil {
+			return buildErrorMessage(lang, lang.T("error.no_calendar"))
+		}
+		if !FeedsEnabled() {
+			return buildErrorMessage(lang, lang.T("caldav.disabled"))
+		}
+
+		var text = lang.T("caldav.show", CalDAVURL(), bot.ChatID)
+		if len(payload) > 0 {
+			switch payload[0] {
+			case "new":
+				text += lang.T("caldav.password", IssueCalDAVPassword(bot.ChatID))
+			case "revoke":
+				if RevokeCalDAVPassword(bot.ChatID) {
+					Notify(update.CallbackQuery, DONE, lang.T("alert.password_revoked"))
+				} else {
+					Notify(update.CallbackQuery, BLOCK, lang.T("alert.no_password"))
+				}
+			}
+		}
+
+		showMessage(update, text, genDefaultEditOpt(
+			[]tgui.InlineButton{
+				tgui.InlineCaller(lang.T("btn.new_password"), "/caldav", "new"),
+				tgui.InlineCaller(lang.T("btn.revoke_password"), "/caldav", "revoke"),
+			},
+			[]tgui.InlineButton{closeButton(lang)},
+		))
 		return nil
 	},
 }
 
+var statusHandler = robot.Command{
+	Trigger: "/status",
+	ReplyAt: message.MESSAGE,
+	CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+		if !config.IsAdmin(bot.ChatID) {
+			return nil
+		}
+
+		var (
+			lang   = extractLanguage(bot, update)
+			events int
+		)
+		for _, calendar := range organizers {
+			events += len(calendar.dates)
+		}
+		storage := config.Storage
+		if storage == "" {
+			storage = "-"
+		}
+
+		return genDefaultMessage(LOGO, lang.T("status.show",
+			len(organizers), events, len(preferences), storage, config.location,
+		), tgui.Wrap(closeButton(lang)))
+	},
+}
+
+/* --- MIDDLEWARE --- */
+
+// requests is the number of updates sent by each user in the current rate limit period
+var requests = struct {
+	sync.Mutex
+	count map[int64]int
+	since time.Time
+}{count: map[int64]int{}}
+
+// guard wraps the commands so that users exceeding the rate limit are ignored,
+// only one update at a time can access the bot's data and replies go through telegram
+func guard(commands ...robot.Command) []robot.Command {
+	for i, command := range commands {
+		callFunc := command.CallFunc
+		commands[i].CallFunc = func(bot *robot.Bot, update *message.Update) message.Any {
+			if isRateLimited(bot.ChatID) {
+				if update.CallbackQuery != nil {
+					Notify(update.CallbackQuery, BLOCK, LanguageOf(bot.ChatID).T("alert.rate_limited"))
+				}
+				return nil
+			}
+
+			dataLock.Lock()
+			defer dataLock.Unlock()
+			if msg := callFunc(bot, update); msg != nil {
+				telegram.Send(bot.ChatID, msg)
+			}
+			return nil
+		}
+	}
+	return commands
+}
+
+// isRateLimited counts a new request of the user and tells if it exceeded the configured limit
+func isRateLimited(userID int64) bool {
+	var limit = config.RateLimit
+	if limit.Requests <= 0 || config.IsAdmin(userID) {
+		return false
+	}
+
+	requests.Lock()
+	defer requests.Unlock()
+
+	if clock.Now().Sub(requests.since) >= time.Duration(limit.Period) {
+		requests.count = map[int64]int{}
+		requests.since = clock.Now()
+	}
+	requests.count[userID]++
+	return requests.count[userID] > limit.Requests
+}
+
 /* --- UTILITIES --- */
 
+// saveData saves the bot's data on the configured storage logging any error
+func saveData() {
+	if err := SaveData(config.Storage); err != nil {
+		log.Println("Storage error: ", err)
+	}
+}
+
 // extractText grabs the text from a given update
 func extractText(update *message.Update) (content string) {
 	if update.CallbackQuery != nil {
@@ -336,7 +1221,7 @@ func extractPayload(update *message.Update) (payload []string) {
 }
 
 // extractFieldValue extract the command from an update, remove trigger and split
-// only the next word to the rest (used in /set and /edit)
+// only the next word to the rest (used in /edit)
 func extractFieldValue(update *message.Update) (field string, value string) {
 	var command = extractText(update)
 	if command == "" {
@@ -345,18 +1230,21 @@ func extractFieldValue(update *message.Update) (field string, value string) {
 
 	if ind := strings.IndexRune(command, ' '); ind > 0 {
 		command = strings.TrimSpace(command[ind+1:])
-		ind = strings.IndexRune(command, ' ')
-		if ind > 0 {
+		if ind = strings.IndexRune(command, ' '); ind > 0 {
 			field, value = command[:ind], command[ind+1:]
+		} else {
+			field = command
 		}
 	}
 	return
 }
 
-func botUsername() (username string) {
-	var res, err = message.API().GetMe()
-	if err == nil && res.Result != nil {
-		username = res.Result.Username
+// extractLanguage grabs the language of the user who sent the update
+func extractLanguage(bot *robot.Bot, update *message.Update) Language {
+	if callback := update.CallbackQuery; callback != nil && callback.From != nil {
+		return DetectLanguage(*callback.From)
+	} else if msg := update.FromMessage(); msg != nil && msg.From != nil {
+		return DetectLanguage(*msg.From)
 	}
-	return
+	return LanguageOf(bot.ChatID)
 }
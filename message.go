@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"html"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/DazFather/parrbot/message"
 	"github.com/DazFather/parrbot/robot"
 	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
 )
 
 /* --- EMOJI ICONS --- */
@@ -25,96 +31,760 @@ const (
 	LOGO      icon = "🐦"
 	CALENDAR  icon = "📅"
 	PEOPLE    icon = "👥"
+	EVENT     icon = "🎟"
+	LANGUAGE  icon = "🌐"
+	SETTINGS  icon = "⚙️"
+	QUESTION  icon = "💬"
 )
 
 func (emoji icon) Text(s string) string {
+	if emoji == "" {
+		return s
+	}
 	return fmt.Sprint(emoji, " ", s)
 }
 
 /* --- FREQUENT BUTTONS --- */
-var (
-	BTN_CLOSE   = closeCaller(CLOSE.Text("Close"), "")
-	BTN_CANCEL  = closeCaller(CANCEL.Text("Cancel"), DONE.Text("Operation cancelled"))
-	BTN_DELETED = closeCaller(CLOSE.Text("Close"), DONE.Text("Deleted"))
-)
+
+func closeButton(lang Language) tgui.InlineButton {
+	return closeCaller(CLOSE.Text(lang.T("btn.close")), "")
+}
+
+func cancelButton(lang Language) tgui.InlineButton {
+	return closeCaller(CANCEL.Text(lang.T("btn.cancel")), DONE.Text(lang.T("alert.cancelled")))
+}
+
+func deletedButton(lang Language) tgui.InlineButton {
+	return closeCaller(CLOSE.Text(lang.T("btn.close")), DONE.Text(lang.T("alert.deleted")))
+}
+
+func backButton(lang Language, trigger string, payload ...string) tgui.InlineButton {
+	return tgui.InlineCaller(BACK.Text(lang.T("btn.back")), trigger, payload...)
+}
 
 /* --- MESSAGE BUILDERS --- */
 
-func buildCalendarMessage(date Date, text string) message.Text {
+func buildCalendarMessage(lang Language, c *Calendar, userID int64, date Date) message.Text {
 	var (
-		month     = date.Month()
-		monthDays = date.MonthEnd().Day()
-		weekday   = date.MonthStart().Week()
-		buttons   = make([]tgui.InlineButton, monthDays+weekday)
-		row       []tgui.InlineButton
-		now       = Now()
+		month   = lang.Format(date.Time, "January 2006")
+		now     = Now()
+		blocked = alertCaller(BLOCK, "", lang.T("alert.day_blocked"))
 	)
 
-	for i := 0; i < weekday; i++ {
-		buttons[i] = alertCaller(BLOCK, "", "Cannot create an event in this day")
+	date = date.MonthStart()
+	keyboard := genMonthGrid(lang, userID, date, []tgui.InlineButton{
+		tgui.InlineCaller("⏮", "/publish", string(date.Skip(0, -1, 0).Formatted()), MONTH_VIEW),
+		tgui.InlineCaller(month, "/publish", string(date.Formatted()), "months"),
+		tgui.InlineCaller("⏭", "/publish", string(date.Skip(0, 1, 0).Formatted()), MONTH_VIEW),
+	}, func(day Date) tgui.InlineButton {
+		var dates []FormattedDate
+		if c != nil {
+			dates = c.DatesOn(day)
+		}
+
+		switch {
+		case len(dates) > 0:
+			attendee := 0
+			for _, d := range dates {
+				attendee += c.CountAttendee(d)
+			}
+			return tgui.InlineCaller(fmt.Sprint(day.Day(), EVENT, attendee), "/publish", string(day.Formatted()), "day")
+		case day.IsBefore(now):
+			return blocked
+		default:
+			return tgui.InlineCaller(fmt.Sprint(day.Day()), "/publish", string(day.Formatted()), "add")
+		}
+	})
+
+	return genDefaultMessage(CALENDAR, lang.T("publish.select_day", month), append(keyboard,
+		[]tgui.InlineButton{
+			tgui.InlineCaller("☑️ "+lang.T("btn.select_days"), "/publish", string(date.Formatted()), SELECT_VIEW),
+			tgui.InlineCaller("🌞 "+lang.T("btn.all_day"), "/publish", string(date.Formatted()), RANGE_VIEW),
+		},
+		[]tgui.InlineButton{
+			cancelButton(lang),
+			tgui.InlineCaller(REFRESH.Text(lang.T("btn.refresh")), "/publish", string(date.Formatted()), MONTH_VIEW),
+		},
+	)...)
+}
+
+// Payloads of /publish used to select the days of an all-day event
+const (
+	RANGE_VIEW = "range" // month grid where the first and then the last day are selected
+	SPAN_ADD   = "span"  // adds the all-day event going from the first to the last day
+)
+
+// buildRangeMessage is the month grid used to select the days of an all-day
+// event: from is nil until the first one is selected, then the last one is asked
+func buildRangeMessage(lang Language, c *Calendar, userID int64, date Date, from *Date) message.Text {
+	var (
+		month   = lang.Format(date.Time, "January 2006")
+		first   = Now().DayStart()
+		blocked = alertCaller(BLOCK, "", lang.T("alert.day_blocked"))
+		text    = lang.T("publish.select_first", month)
+		extra   []string
+	)
+	if from != nil {
+		first = from.DayStart()
+		text = lang.T("publish.select_last", lang.Format(from.Time, DAY_FORMAT))
+		extra = []string{string(first.Formatted())}
 	}
 
-	row = buttons[weekday:]
 	date = date.MonthStart()
-	for i := range row {
+	keyboard := genMonthGrid(lang, userID, date, []tgui.InlineButton{
+		tgui.InlineCaller("⏮", "/publish", append([]string{string(date.Skip(0, -1, 0).Formatted()), RANGE_VIEW}, extra...)...),
+		alertCaller("", month, month),
+		tgui.InlineCaller("⏭", "/publish", append([]string{string(date.Skip(0, 1, 0).Formatted()), RANGE_VIEW}, extra...)...),
+	}, func(day Date) tgui.InlineButton {
+		var label = fmt.Sprint(day.Day())
+		if c != nil && len(c.DatesOn(day)) > 0 {
+			label += string(EVENT)
+		}
+
+		switch {
+		case day.DayStart().IsBefore(first):
+			return blocked
+		case from == nil:
+			return tgui.InlineCaller(label, "/publish", string(day.Formatted()), RANGE_VIEW, string(day.Formatted()))
+		case day.IsSameDay(*from):
+			return tgui.InlineCaller(DONE.Text(label), "/publish", string(first.Formatted()), SPAN_ADD, string(day.Formatted()))
+		default:
+			return tgui.InlineCaller(label, "/publish", string(first.Formatted()), SPAN_ADD, string(day.Formatted()))
+		}
+	})
+
+	return genDefaultMessage(CALENDAR, text, append(keyboard, []tgui.InlineButton{
+		backButton(lang, "/publish", string(date.Formatted()), MONTH_VIEW),
+		cancelButton(lang),
+	})...)
+}
+
+// genMonthGrid generates the keyboard of a month with the given header, the
+// buttons of its days are generated by cell while the others are blocked
+func genMonthGrid(lang Language, userID int64, date Date, header []tgui.InlineButton, cell func(day Date) tgui.InlineButton) [][]tgui.InlineButton {
+	var (
+		first       = WeekStartOf(userID)
+		weekNumbers = ShowWeekNumbers(userID)
+		blocked     = alertCaller(BLOCK, "", lang.T("alert.day_blocked"))
+		weekdays    []tgui.InlineButton
+	)
+
+	if weekNumbers {
+		weekdays = append(weekdays, alertCaller("", "#", lang.T("alert.week_numbers")))
+	}
+	for i := 0; i < 7; i++ {
+		weekday := lang.Weekday(time.Weekday((int(first) + i) % 7))
+		weekdays = append(weekdays, alertCaller("", short(weekday), weekday))
+	}
+	keyboard := [][]tgui.InlineButton{header, weekdays}
+
+	var (
+		offset = date.WeekOffset(first)
+		weeks  = (offset+date.MonthEnd().Day()-1)/7 + 1
+	)
+	for w := 0; w < weeks; w++ {
 		var (
-			label string = fmt.Sprint(i + 1)
-			day   Date   = date.Skip(0, 0, i)
+			start = date.Skip(0, 0, w*7-offset)
+			row   = make([]tgui.InlineButton, 0, 8)
 		)
+		if weekNumbers {
+			_, number := start.Skip(0, 0, 3).ISOWeek()
+			row = append(row, alertCaller("", lang.T("grid.week_number", number), lang.T("alert.week_number", number)))
+		}
 
-		if day.IsBefore(now) {
-			row[i] = alertCaller(BLOCK, "", "Cannot create an event in this day")
-		} else {
-			row[i] = tgui.InlineCaller(label, "/publish", string(day.Formatted()), "add")
+		for i := 0; i < 7; i++ {
+			if day := start.Skip(0, 0, i); day.Month() != date.Month() {
+				row = append(row, blocked)
+			} else {
+				row = append(row, cell(day))
+			}
 		}
+		keyboard = append(keyboard, row)
 	}
+	return keyboard
+}
 
-	keyboard := append([][]tgui.InlineButton{{
-		tgui.InlineCaller("⏮", "/publish", string(date.Skip(0, -1, 0).Formatted()), "refresh"),
-		alertCaller(BLOCK, month.String(), "This feature is not yet avaiable"),
-		tgui.InlineCaller("⏭", "/publish", string(date.Skip(0, 1, 0).Formatted()), "refresh"),
-	}}, tgui.Arrange(7, buttons...)...)
+// buildCreatedMessage confirms to the organizer the creation of the calendar, with its link
+func buildCreatedMessage(lang Language, c Calendar) message.Text {
+	return genDefaultMessage(
+		DONE,
+		lang.T("publish.created", GetShareLink(telegram.Username(), c)),
+		[]tgui.InlineButton{
+			backButton(lang, "/start"),
+			closeButton(lang),
+		},
+	)
+}
+
+func buildMonthPickerMessage(lang Language, date Date) message.Text {
+	var (
+		year    = date.MonthStart().Skip(0, -int(date.Month())+1, 0)
+		buttons = make([]tgui.InlineButton, 12)
+	)
 
-	row = keyboard[len(keyboard)-1]
-	for i := len(row); i < 7; i++ {
-		row = append(row, alertCaller(BLOCK, "", "Cannot create an event in this day"))
+	for i := range buttons {
+		month := year.Skip(0, i, 0)
+		buttons[i] = tgui.InlineCaller(short(lang.Month(month.Month())), "/publish", string(month.Formatted()), MONTH_VIEW)
 	}
-	keyboard[len(keyboard)-1] = row
 
-	return genDefaultMessage(CALENDAR, text+month.String(), append(keyboard, []tgui.InlineButton{
-		BTN_CANCEL,
-		tgui.InlineCaller("🔄Refresh", "/publish", string(date.Formatted()), "refresh"),
+	keyboard := append([][]tgui.InlineButton{{
+		tgui.InlineCaller(fmt.Sprint("⏪ ", year.Year()-1), "/publish", string(date.Skip(-1, 0, 0).Formatted()), "months"),
+		tgui.InlineCaller(fmt.Sprint("⏩ ", year.Year()+1), "/publish", string(date.Skip(1, 0, 0).Formatted()), "months"),
+	}}, tgui.Arrange(3, buttons...)...)
+
+	return genDefaultMessage(CALENDAR, lang.T("publish.select_month", year.Year()), append(keyboard, []tgui.InlineButton{
+		backButton(lang, "/publish", string(date.Formatted()), MONTH_VIEW),
+		tgui.InlineCaller(CALENDAR.Text(lang.T("btn.today")), "/publish", "today", MONTH_VIEW),
 	})...)
 }
 
-func buildDateListMessage(c Calendar, userID int64) message.Text {
-	var kbd = make([][]tgui.InlineButton, len(c.dates)+1)
+func buildDayMessage(lang Language, c Calendar, day Date) message.Text {
+	var (
+		dates = c.DatesOn(day)
+		kbd   = make([][]tgui.InlineButton, 0, len(dates)+1)
+		text  = lang.T("day.title", lang.Format(day.Time, "Monday 02 January 2006"))
+	)
+
+	for _, date := range dates {
+		n := c.CountAttendee(date)
+		text += fmt.Sprint("\n🕒 ", c.Describe(date, lang), " - ", PEOPLE, n)
+		if len(c.Overlapping(date)) > 0 {
+			text += " ⚠️ " + lang.T("day.overlapping")
+		}
+		kbd = append(kbd, []tgui.InlineButton{
+			alertCaller(EVENT, c.Describe(date, lang), lang.Plural("day.attendee", n)),
+			tgui.InlineCaller("⧉", "/publish", string(date), COPY_VIEW, string(date)),
+			tgui.InlineCaller("🔗", "/link", string(date)),
+		})
+	}
+
+	row := []tgui.InlineButton{backButton(lang, "/publish", string(day.Formatted()), MONTH_VIEW)}
+	if !day.IsBefore(Now()) {
+		row = append(row, tgui.InlineCaller(lang.T("btn.add_event"), "/publish", string(day.Formatted()), "add"))
+	}
 
-	i := 0
-	for date, event := range c.dates {
-		var caption string = date.Beautify()
-		if n := event.countAttendee(); n > 0 {
-			if event.hasJoined(userID) {
-				caption = fmt.Sprint(DONE, " ", caption, " - ", PEOPLE, n-1, " + 1 (You)")
+	return genDefaultMessage(CALENDAR, text, append(kbd, row)...)
+}
+
+// buildEventMessage shows a single date of the calendar with the button to join it
+func buildEventMessage(lang Language, c Calendar, date FormattedDate, userID int64) message.Text {
+	var (
+		allDates = tgui.InlineCaller(lang.T("btn.open_calendar"), "/start", c.invitation)
+		event    = c.dates[date]
+	)
+	if event == nil {
+		return genDefaultMessage(BLOCK, lang.T("error.invalid_event"), []tgui.InlineButton{allDates, closeButton(lang)})
+	}
+
+	var join tgui.InlineButton
+	switch {
+	case event.hasJoined(userID):
+		join = alertCaller(DONE, lang.T("btn.joined"), lang.T("error.already_joined"))
+	case !c.HasFreeSeats(date):
+		join = alertCaller(BLOCK, lang.T("btn.event_full"), lang.T("error.event_full"))
+	default:
+		join = tgui.InlineCaller(lang.T("btn.join"), "/join", c.invitation, string(date))
+	}
+	return genDefaultMessage(icon(""), buildEventText(lang, c, date), tgui.Wrap(join), []tgui.InlineButton{allDates, closeButton(lang)})
+}
+
+// Payload of the join button that ignores the overlapping events
+const JOIN_ANYWAY = "force"
+
+// buildConflictMessage asks the confirmation to join an event overlapping others already joined
+func buildConflictMessage(lang Language, c Calendar, date FormattedDate, conflicts []Conflict) (text string, kbd [][]tgui.InlineButton) {
+	text = lang.T("conflict.title", html.EscapeString(c.name), c.Describe(date, lang))
+	for _, conflict := range conflicts {
+		text += lang.T("conflict.event", html.EscapeString(conflict.Calendar.name), conflict.Calendar.Describe(conflict.Date, lang))
+	}
+	text += lang.T("conflict.confirm")
+
+	return text, [][]tgui.InlineButton{{
+		tgui.InlineCaller(CONFIRM.Text(lang.T("btn.join_anyway")), "/join", c.invitation, string(date), JOIN_ANYWAY),
+		cancelButton(lang),
+	}}
+}
+
+// buildEventText describes a date of the calendar with its attendees and free seats
+func buildEventText(lang Language, c Calendar, date FormattedDate) string {
+	var (
+		n    = c.CountAttendee(date)
+		text = lang.T("event.show", html.EscapeString(c.name), html.EscapeString(c.description), c.Describe(date, lang), lang.Plural("day.attendee", n))
+	)
+	if free := c.capacity - n; c.capacity > 0 && free >= 0 {
+		text += lang.Plural("event.seats", free)
+	}
+	return text
+}
+
+// Max number of dates displayed in a single page of the date list
+const DATE_LIST_SIZE = 8
+
+func buildDateListMessage(lang Language, c Calendar, userID int64, page int, filter DateFilter) message.Text {
+	var (
+		dates = filter.Apply(c, userID)
+		pages = (len(dates)-1)/DATE_LIST_SIZE + 1
+		kbd   [][]tgui.InlineButton
+		month string
+		text  = lang.T("list.title", c.name, c.description)
+	)
+
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	if len(dates) == 0 {
+		text += lang.T("list.empty")
+	}
+
+	last := (page + 1) * DATE_LIST_SIZE
+	if last > len(dates) {
+		last = len(dates)
+	}
+	for _, date := range dates[page*DATE_LIST_SIZE : last] {
+		if parsed, err := date.Parse(); err == nil && lang.Format(parsed.Time, "January 2006") != month {
+			month = lang.Format(parsed.Time, "January 2006")
+			kbd = append(kbd, tgui.Wrap(alertCaller(CALENDAR, month, month)))
+		}
+
+		var caption string = c.Describe(date, lang)
+		if n := c.CountAttendee(date); n > 0 {
+			if c.dates[date].hasJoined(userID) {
+				caption = fmt.Sprint(DONE, " ", caption, " - ", PEOPLE, n-1, " + 1 ", lang.T("list.you"))
 			} else {
-				caption += fmt.Sprint("- ", PEOPLE, n)
+				caption += fmt.Sprint(" - ", PEOPLE, n)
 			}
 		}
-		kbd[i] = tgui.Wrap(tgui.InlineCaller(caption, "/join", c.invitation, string(date)))
-		i++
+		kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(caption, "/join", c.invitation, string(date))))
+	}
+
+	var filters []tgui.InlineButton
+	for _, f := range []struct {
+		flag  rune
+		label string
+	}{
+		{FILTER_FUTURE, "⏳ " + lang.T("filter.future")},
+		{FILTER_JOINED, "🙋 " + lang.T("filter.joined")},
+		{FILTER_FREE, "🪑 " + lang.T("filter.free")},
+	} {
+		if filter.Has(f.flag) {
+			f.label = DONE.Text(f.label)
+		}
+		filters = append(filters, tgui.InlineCaller(f.label, "/start", c.invitation, "list", "0", filter.Toggle(f.flag).String()))
+	}
+	kbd = append(kbd, filters)
+
+	if pages > 1 {
+		var nav []tgui.InlineButton
+		if page > 0 {
+			nav = append(nav, tgui.InlineCaller("⏮", "/start", c.invitation, "list", fmt.Sprint(page-1), filter.String()))
+		}
+		nav = append(nav, alertCaller(icon("📄"), fmt.Sprint(page+1, "/", pages), lang.T("list.page", page+1, pages)))
+		if page < pages-1 {
+			nav = append(nav, tgui.InlineCaller("⏭", "/start", c.invitation, "list", fmt.Sprint(page+1), filter.String()))
+		}
+		kbd = append(kbd, nav)
+	}
+
+	kbd = append(kbd, []tgui.InlineButton{
+		tgui.InlineCaller(REFRESH.Text(lang.T("btn.refresh")), "/start", c.invitation, "list", fmt.Sprint(page), filter.String()),
+		closeButton(lang),
+	}, inviteeViewer(lang, c.invitation, userID).switcher(Now(), MONTH_VIEW))
+
+	return genDefaultMessage(icon("🛎"), text, kbd...)
+}
+
+/* --- CALENDAR VIEWS --- */
+
+const (
+	MONTH_VIEW  = "refresh"
+	WEEK_VIEW   = "week"
+	AGENDA_VIEW = "agenda"
+	AGENDA_SIZE = 5
+	// Max number of events per day displayed in the week view
+	WEEK_MAX_EVENTS = 5
+)
+
+// viewer describes how to navigate between the views of a calendar and how to open its events
+type viewer struct {
+	lang   Language
+	userID int64
+	view   func(label string, date Date, view string) tgui.InlineButton
+	open   func(label string, date FormattedDate) tgui.InlineButton
+}
+
+// organizerViewer navigates the views of the organizer's own calendar, using the /publish callbacks
+func organizerViewer(lang Language, userID int64) viewer {
+	return viewer{
+		lang:   lang,
+		userID: userID,
+		view: func(label string, date Date, view string) tgui.InlineButton {
+			return tgui.InlineCaller(label, "/publish", string(date.Formatted()), view)
+		},
+		open: func(label string, date FormattedDate) tgui.InlineButton {
+			return tgui.InlineCaller(label, "/publish", string(date), "day")
+		},
+	}
+}
+
+// inviteeViewer navigates the views of the calendar with the given invitation, using the /start callbacks
+func inviteeViewer(lang Language, invitation string, userID int64) viewer {
+	return viewer{
+		lang:   lang,
+		userID: userID,
+		view: func(label string, date Date, view string) tgui.InlineButton {
+			if view == MONTH_VIEW {
+				return tgui.InlineCaller(label, "/start", invitation)
+			}
+			return tgui.InlineCaller(label, "/start", invitation, view, string(date.Formatted()))
+		},
+		open: func(label string, date FormattedDate) tgui.InlineButton {
+			return tgui.InlineCaller(label, "/join", invitation, string(date))
+		},
+	}
+}
+
+// switcher generates the row used to move from the current view to the others
+func (v viewer) switcher(date Date, current string) (row []tgui.InlineButton) {
+	for _, view := range []struct{ name, label string }{
+		{MONTH_VIEW, CALENDAR.Text(v.lang.T("view.calendar"))},
+		{WEEK_VIEW, "📆 " + v.lang.T("view.week")},
+		{AGENDA_VIEW, "📋 " + v.lang.T("view.agenda")},
+	} {
+		if view.name != current {
+			row = append(row, v.view(view.label, date, view.name))
+		}
+	}
+	return
+}
+
+// eventLabel generates a short caption of an event showing time, attendee and if the user joined
+func (v viewer) eventLabel(c Calendar, date FormattedDate, layout string) string {
+	var label = date.Beautify(v.lang)
+	if parsed, err := date.Parse(); err == nil && c.Days(date) > 0 {
+		label = strings.TrimSpace(v.lang.Format(parsed.Time, strings.TrimSuffix(layout, "15:04")) + " " + v.lang.T("date.all_day_short"))
+	} else if err == nil {
+		label = v.lang.Format(parsed.Time, layout)
+	}
+
+	if event := c.dates[date]; event != nil && event.hasJoined(v.userID) {
+		label = DONE.Text(label)
+	}
+	if n := c.CountAttendee(date); n > 0 {
+		label += fmt.Sprint(" ", PEOPLE, n)
+	}
+	return label
+}
+
+func buildWeekMessage(c Calendar, date Date, v viewer) message.Text {
+	var (
+		lang  = v.lang
+		start = date.WeekStart(WeekStartOf(v.userID))
+		end   = start.Skip(0, 0, 6)
+		kbd   = [][]tgui.InlineButton{{
+			v.view("⏮", start.Skip(0, 0, -7), WEEK_VIEW),
+			v.view(start.Format("02/01")+" - "+end.Format("02/01"), Now(), WEEK_VIEW),
+			v.view("⏭", start.Skip(0, 0, 7), WEEK_VIEW),
+		}}
+	)
+
+	_, week := start.Skip(0, 0, 3).ISOWeek()
+	for i := 0; i < 7; i++ {
+		var (
+			day      = start.Skip(0, 0, i)
+			fullDate = lang.Format(day.Time, "Monday 02 January 2006")
+			row      = tgui.Wrap(alertCaller(CALENDAR, lang.Format(day.Time, "Mon 02"), fullDate))
+		)
+
+		events := c.DatesOn(day)
+		for j, date := range events {
+			if j == WEEK_MAX_EVENTS {
+				row = append(row, alertCaller(EVENT, fmt.Sprint("+", len(events)-j), lang.Plural("week.events", len(events), fullDate)))
+				break
+			}
+			row = append(row, v.open(v.eventLabel(c, date, "15:04"), date))
+		}
+		kbd = append(kbd, row)
+	}
+
+	return genDefaultMessage(
+		icon("📆"),
+		lang.T("week.title", c.name, week, start.Format("02/01/2006"), end.Format("02/01/2006")),
+		append(kbd, v.switcher(date, WEEK_VIEW), tgui.Wrap(closeButton(lang)))...,
+	)
+}
+
+func buildAgendaMessage(c Calendar, from Date, v viewer) message.Text {
+	var (
+		lang     = v.lang
+		upcoming []FormattedDate
+		text     = lang.T("agenda.title", c.name, c.description, from.Format("02/01/2006"))
+		kbd      [][]tgui.InlineButton
+		nav      []tgui.InlineButton
+		now      = Now()
+	)
+
+	for _, date := range c.SortedDates() {
+		if start, end, err := c.Interval(date); err == nil && (!start.Before(from.Time) || c.Days(date) > 0 && end.After(from.Time)) {
+			upcoming = append(upcoming, date)
+		}
+	}
+
+	if from.IsAfter(now) {
+		nav = append(nav, v.view("⏮ "+lang.T("btn.today"), now, AGENDA_VIEW))
+	}
+	if len(upcoming) > AGENDA_SIZE {
+		if next, err := upcoming[AGENDA_SIZE].Parse(); err == nil {
+			nav = append(nav, v.view("⏭", next, AGENDA_VIEW))
+		}
+		upcoming = upcoming[:AGENDA_SIZE]
+	}
+
+	if len(upcoming) == 0 {
+		text += "\n" + lang.T("agenda.empty")
+	}
+	for _, date := range upcoming {
+		var n = c.CountAttendee(date)
+		text += fmt.Sprint("\n🕒 <b>", c.Describe(date, lang), "</b> - ", PEOPLE, n)
+		if event := c.dates[date]; event != nil && event.hasJoined(v.userID) {
+			text += " " + lang.T("list.you")
+		}
+		kbd = append(kbd, tgui.Wrap(v.open(v.eventLabel(c, date, "Mon 02/01 15:04"), date)))
 	}
-	kbd[i] = []tgui.InlineButton{
-		tgui.InlineCaller(REFRESH.Text("Refresh"), "/start", c.invitation),
-		BTN_CLOSE,
+	if len(nav) > 0 {
+		kbd = append(kbd, nav)
 	}
 
 	return genDefaultMessage(
-		icon("🛎"),
-		"<b>"+c.name+"</b>\n"+c.description+"\n\n<i>Tap one (or more) of following dates to join</i>",
-		kbd...,
+		icon("📋"),
+		text,
+		append(kbd, v.switcher(from, AGENDA_VIEW), tgui.Wrap(closeButton(lang)))...,
+	)
+}
+
+// genLanguageKeyboard generates the keyboard to choose one of the supported languages
+func genLanguageKeyboard(current Language) [][]tgui.InlineButton {
+	var buttons = make([]tgui.InlineButton, 0, len(locales))
+	for _, l := range Languages() {
+		caption := l.Name()
+		if l == current {
+			caption = DONE.Text(caption)
+		}
+		buttons = append(buttons, tgui.InlineCaller(caption, "/language", string(l)))
+	}
+
+	return append(tgui.Arrange(2, buttons...), []tgui.InlineButton{
+		backButton(current, "/settings"),
+		closeButton(current),
+	})
+}
+
+// genSettingsKeyboard generates the keyboard to change the preferences of a user
+func genSettingsKeyboard(lang Language, userID int64) [][]tgui.InlineButton {
+	var (
+		first   = WeekStartOf(userID)
+		numbers = toggler(ShowWeekNumbers(userID))
+		days    []tgui.InlineButton
+	)
+
+	for _, day := range []time.Weekday{time.Monday, time.Sunday, time.Saturday} {
+		caption := lang.Weekday(day)
+		if day == first {
+			caption = DONE.Text(caption)
+		}
+		days = append(days, tgui.InlineCaller(caption, "/settings", "weekstart", fmt.Sprint(int(day))))
+	}
+
+	return [][]tgui.InlineButton{
+		{tgui.InlineCaller(LANGUAGE.Text(lang.Name()), "/language")},
+		days,
+		{tgui.InlineCaller(lang.T("btn.week_numbers", numbers), "/settings", "weeknumbers")},
+		{backButton(lang, "/start"), closeButton(lang)},
+	}
+}
+
+// buildWebhooksText describes the webhooks registered by a user
+func buildWebhooksText(lang Language, userID int64) string {
+	var text = lang.T("webhook.title")
+	if len(webhooks[userID]) == 0 {
+		return text + lang.T("webhook.empty")
+	}
+	for _, hook := range webhooks[userID] {
+		text += fmt.Sprintf("\n🔗 <code>%s</code> %s", hook.ID, html.EscapeString(hook.URL))
+	}
+	return text
+}
+
+// genWebhooksKeyboard generates the keyboard to test and remove the webhooks of a user
+func genWebhooksKeyboard(lang Language, userID int64) (rows [][]tgui.InlineButton) {
+	for _, hook := range webhooks[userID] {
+		rows = append(rows, []tgui.InlineButton{
+			tgui.InlineCaller(lang.T("btn.test_webhook", hook.ID), "/webhook", "test", hook.ID),
+			tgui.InlineCaller(lang.T("btn.remove_webhook", hook.ID), "/webhook", "remove", hook.ID),
+		})
+	}
+	return append(rows,
+		[]tgui.InlineButton{tgui.InlineCaller(lang.T("btn.delivery_log"), "/webhook", "log")},
+		[]tgui.InlineButton{backButton(lang, "/start"), closeButton(lang)},
 	)
 }
 
+// buildDeliveryLog describes the given deliveries made to the webhooks
+func buildDeliveryLog(lang Language, log []Delivery) string {
+	var text = lang.T("webhook.log")
+	if len(log) == 0 {
+		return text + lang.T("webhook.log_empty")
+	}
+	for _, delivery := range log {
+		outcome := DONE
+		if delivery.Error != "" {
+			outcome = BLOCK
+		}
+		text += "\n" + outcome.Text(lang.T("webhook.delivery",
+			lang.Format(delivery.Time.In(config.location), "02 Jan 15:04:05"),
+			delivery.Event, delivery.Webhook, delivery.Attempt, delivery.Status,
+		))
+		if delivery.Error != "" {
+			text += " <i>" + html.EscapeString(delivery.Error) + "</i>"
+		}
+	}
+	return text
+}
+
+// buildFeedbackRequest asks an attendee to rate an event that occurred
+func buildFeedbackRequest(lang Language, c Calendar, date FormattedDate) message.Text {
+	var row []tgui.InlineButton
+	for rating := MIN_RATING; rating <= MAX_RATING; rating++ {
+		row = append(row, tgui.InlineCaller(fmt.Sprint(rating, "⭐"), "/rate", c.invitation, string(date), strconv.Itoa(rating)))
+	}
+	return genDefaultMessage(icon("⭐"), lang.T("feedback.request", html.EscapeString(c.name), c.Describe(date, lang)), row, tgui.Wrap(closeButton(lang)))
+}
+
+// buildFeedbackSummary shows to the organizer the ratings of the calendar and of its archived dates
+func buildFeedbackSummary(lang Language, c Calendar) (text string, kbd [][]tgui.InlineButton) {
+	if average, count := c.Rating(); count > 0 {
+		text = lang.Plural("feedback.summary", count, html.EscapeString(c.name), average)
+	} else {
+		text = lang.T("feedback.none", html.EscapeString(c.name))
+	}
+
+	for _, date := range c.SortedArchive() {
+		average, count := c.archive[date].Rating()
+		if count == 0 {
+			continue
+		}
+		if len(kbd) == DATE_LIST_SIZE {
+			break
+		}
+		caption := fmt.Sprintf("%s - ⭐%.1f (%d)", c.Describe(date, lang), average, count)
+		kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(caption, "/feedback", string(date))))
+	}
+	return text, append(kbd, tgui.Wrap(closeButton(lang)))
+}
+
+// buildFeedbackDetail shows to the organizer the ratings and comments of an archived date
+func buildFeedbackDetail(lang Language, c Calendar, date FormattedDate) string {
+	var (
+		event          = c.archive[date]
+		average, count = event.Rating()
+		votes          = make([]int, MAX_RATING+1)
+		text           = lang.Plural("feedback.detail", count, html.EscapeString(c.name), c.Describe(date, lang), average, event.countAttendee())
+	)
+	for _, feedback := range event.feedback {
+		votes[feedback.Rating]++
+	}
+	for rating := MAX_RATING; rating >= MIN_RATING; rating-- {
+		text += fmt.Sprint("\n", rating, "⭐ ", strings.Repeat("▇", votes[rating]), " ", votes[rating])
+	}
+	var attendees = make([]int64, 0, len(event.feedback))
+	for userID := range event.feedback {
+		attendees = append(attendees, userID)
+	}
+	sort.Slice(attendees, func(i, j int) bool { return attendees[i] < attendees[j] })
+	for _, userID := range attendees {
+		if feedback := event.feedback[userID]; feedback.Comment != "" {
+			text += fmt.Sprint("\n\n💬 <i>", html.EscapeString(feedback.Comment), "</i> (", feedback.Rating, "⭐)")
+		}
+	}
+	return text
+}
+
+// buildHistoryMessage shows to the organizer a page of the events that occurred, most recent first
+func buildHistoryMessage(lang Language, c Calendar, page int) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		dates = c.SortedArchive()
+		pages = (len(dates)-1)/DATE_LIST_SIZE + 1
+	)
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	text = lang.Plural("history.title", len(dates), html.EscapeString(c.name))
+	if len(dates) == 0 {
+		text += lang.T("history.empty")
+	}
+
+	last := (page + 1) * DATE_LIST_SIZE
+	if last > len(dates) {
+		last = len(dates)
+	}
+	for _, date := range dates[page*DATE_LIST_SIZE : last] {
+		var caption = fmt.Sprint(c.Describe(date, lang), " - ", PEOPLE, c.archive[date].countAttendee())
+		if attended := len(c.archive[date].attended); attended > 0 {
+			caption += fmt.Sprint(" ", CONFIRM, attended)
+		}
+		if average, count := c.archive[date].Rating(); count > 0 {
+			caption += fmt.Sprintf(" ⭐%.1f", average)
+		}
+		kbd = append(kbd, tgui.Wrap(tgui.InlineCaller(caption, "/history", string(date), fmt.Sprint(page))))
+	}
+
+	if pages > 1 {
+		var nav []tgui.InlineButton
+		if page > 0 {
+			nav = append(nav, tgui.InlineCaller("⏮", "/history", fmt.Sprint(page-1)))
+		}
+		nav = append(nav, alertCaller(icon("📄"), fmt.Sprint(page+1, "/", pages), lang.T("list.page", page+1, pages)))
+		if page < pages-1 {
+			nav = append(nav, tgui.InlineCaller("⏭", "/history", fmt.Sprint(page+1)))
+		}
+		kbd = append(kbd, nav)
+	}
+	return text, append(kbd, tgui.Wrap(closeButton(lang)))
+}
+
+// buildRoster shows to the organizer who joined an event and who checked in
+func buildRoster(lang Language, c Calendar, date FormattedDate) string {
+	var (
+		event = c.event(date)
+		text  = lang.Plural("history.detail", event.countAttendee(), html.EscapeString(c.name), c.Describe(date, lang))
+	)
+	if len(event.attended) > 0 {
+		text += lang.Plural("history.attended", len(event.attended))
+	}
+	for i, userID := range event.attendee {
+		var mark string
+		if event.hasAttended(userID) {
+			mark = CONFIRM.Text("")
+		} else if len(event.attended) > 0 {
+			mark = CANCEL.Text("")
+		}
+		text += lang.T("history.attendee", i+1, mark, userID)
+	}
+	if walkIns := event.walkIns(); len(walkIns) > 0 {
+		text += lang.T("history.walk_ins")
+		for i, userID := range walkIns {
+			text += lang.T("history.attendee", i+1, CONFIRM.Text(""), userID)
+		}
+	}
+	return text
+}
+
 /*
 func buildEditorMessage(c Calendar) message.Text {
 	var kbd = make([][]tgui.InlineButton, len(c.dates))
@@ -132,8 +802,43 @@ func buildEditorMessage(c Calendar) message.Text {
 	return message.Text{"", nil}
 }*/
 
-func buildErrorMessage(text string) message.Text {
-	return genDefaultMessage(BLOCK, text, tgui.Wrap(BTN_CLOSE))
+// buildEditMessage asks to confirm the change of a field of the calendar to the
+// suggested value, the error explains why the value can not be set
+func buildEditMessage(lang Language, c Calendar, field, suggested string) (text string, err error) {
+	current, err := FieldOf(c, field)
+	if err != nil {
+		return "", errors.New(lang.T("edit.invalid_field", html.EscapeString(field)))
+	}
+	if err = ValidateEdit(field, suggested); err != nil {
+		if field == "name" || field == "description" {
+			return "", errors.New(lang.Error(err))
+		}
+		return "", errors.New(lang.T("edit.invalid_"+field, html.EscapeString(suggested)))
+	}
+	return lang.T("edit.confirm", field, html.EscapeString(current), html.EscapeString(suggested)), nil
+}
+
+// buildQRCodeMessage sends the QR code of the link as a photo with the given caption
+func buildQRCodeMessage(lang Language, link, caption string) message.Any {
+	if url := publicLink(link); url != "" {
+		link = url
+	}
+	var image, err = QRCodePNG(link)
+	if err != nil {
+		return buildErrorMessage(lang, err.Error())
+	}
+
+	return message.Photo{
+		File: echotron.NewInputFileBytes("qrcode.png", image),
+		Opts: &echotron.PhotoOptions{
+			Caption:     caption,
+			ReplyMarkup: tgui.InlineKeyboard(tgui.Wrap(tgui.Wrap(closeButton(lang)))),
+		},
+	}
+}
+
+func buildErrorMessage(lang Language, text string) message.Text {
+	return genDefaultMessage(BLOCK, text, tgui.Wrap(closeButton(lang)))
 }
 
 func genDefaultEditOpt(rows ...[]tgui.InlineButton) *tgui.EditOptions {
@@ -145,14 +850,15 @@ func genDefaultEditOpt(rows ...[]tgui.InlineButton) *tgui.EditOptions {
 }
 
 func genDefaultMessage(emoji icon, text string, rows ...[]tgui.InlineButton) message.Text {
-	return message.Text{emoji.Text(text), tgui.ToMessageOptions(genDefaultEditOpt(rows...))}
+	return message.Text{Text: emoji.Text(text), Opts: tgui.ToMessageOptions(genDefaultEditOpt(rows...))}
 }
 
 func sendNotification(chatID int64, text string) error {
-	_, err := genDefaultMessage(NOTIF_ON, text, []tgui.InlineButton{
-		BTN_DELETED,
-		tgui.InlineCaller(NOTIF_OFF.Text("Turn off notifications"), "/edit", "notification", "off"),
-	}).Send(chatID)
+	var lang = LanguageOf(chatID)
+	_, err := telegram.Send(chatID, genDefaultMessage(NOTIF_ON, text, []tgui.InlineButton{
+		deletedButton(lang),
+		tgui.InlineCaller(NOTIF_OFF.Text(lang.T("btn.notification_off")), "/edit", "notification", "off"),
+	}))
 
 	return err
 }
@@ -171,7 +877,7 @@ func Notify(callback *message.CallbackQuery, emoji icon, text string) {
 		return
 	}
 
-	callback.AnswerToast(emoji.Text(text), MAX_CACHE_TIME)
+	telegram.Answer(callback, &echotron.CallbackQueryOptions{Text: emoji.Text(text), CacheTime: int(config.AlertCacheTime)})
 }
 
 func Collapse(callback *message.CallbackQuery, emoji icon, message string) {
@@ -181,8 +887,8 @@ func Collapse(callback *message.CallbackQuery, emoji icon, message string) {
 		return
 	}
 
-	callback.Answer(nil)
-	callback.Delete()
+	telegram.Answer(callback, nil)
+	telegram.Delete(callback.Message)
 }
 
 func alerter(trigger string) (command robot.Command, caller func(emoji icon, label, text string) tgui.InlineButton) {
@@ -204,7 +910,18 @@ func alerter(trigger string) (command robot.Command, caller func(emoji icon, lab
 }
 
 func closer(trigger string) (command robot.Command, caller func(label, text string) tgui.InlineButton) {
-	command = tgui.Closer(trigger, false)
+	command = robot.Command{
+		Trigger: trigger,
+		ReplyAt: message.CALLBACK_QUERY,
+		CallFunc: func(bot *robot.Bot, update *message.Update) message.Any {
+			var callback = update.CallbackQuery
+			if text := strings.TrimSpace(strings.TrimPrefix(callback.Data, trigger)); text != "" {
+				telegram.Answer(callback, &echotron.CallbackQueryOptions{Text: text, CacheTime: MAX_CACHE_TIME})
+			}
+			telegram.Delete(callback.Message)
+			return nil
+		},
+	}
 
 	caller = func(label, text string) tgui.InlineButton {
 		if text == "" {
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+/* --- QR CODE --- */
+
+const (
+	QR_MAX_VERSION = 10 // enough for links up to 213 bytes
+	QR_SCALE       = 10 // size in pixels of each module of the rendered images
+	QR_QUIET_ZONE  = 4  // number of light modules around the code
+)
+
+// qrVersion are the properties of a QR code version with error correction level M
+type qrVersion struct {
+	codewords  int   // total number of codewords, data and error correction
+	ecc        int   // error correction codewords of each block
+	blocks     int   // number of blocks the codewords are split into
+	alignments []int // coordinates of the centers of the alignment patterns
+}
+
+var qrVersions = [QR_MAX_VERSION + 1]qrVersion{
+	1:  {26, 10, 1, nil},
+	2:  {44, 16, 1, []int{6, 18}},
+	3:  {70, 26, 1, []int{6, 22}},
+	4:  {100, 18, 2, []int{6, 26}},
+	5:  {134, 24, 2, []int{6, 30}},
+	6:  {172, 16, 4, []int{6, 34}},
+	7:  {196, 18, 4, []int{6, 22, 38}},
+	8:  {242, 22, 4, []int{6, 24, 42}},
+	9:  {292, 22, 5, []int{6, 26, 46}},
+	10: {346, 26, 5, []int{6, 28, 50}},
+}
+
+// capacity is the number of data codewords of the version
+func (v qrVersion) capacity() int {
+	return v.codewords - v.ecc*v.blocks
+}
+
+// QRCode is a grid of modules, true for the dark ones
+type QRCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool // modules of the patterns, not used for the data
+}
+
+// EncodeQR creates the QR code of the text, using byte mode and error correction level M
+func EncodeQR(text string) (*QRCode, error) {
+	var version int
+	for version = 1; version <= QR_MAX_VERSION; version++ {
+		if 4+qrCountBits(version)+len(text)*8 <= qrVersions[version].capacity()*8 {
+			break
+		}
+	}
+	if version > QR_MAX_VERSION {
+		return nil, errors.New("text too long for a QR code")
+	}
+
+	var qr = &QRCode{size: version*4 + 17}
+	qr.modules, qr.function = make([][]bool, qr.size), make([][]bool, qr.size)
+	for y := range qr.modules {
+		qr.modules[y], qr.function[y] = make([]bool, qr.size), make([]bool, qr.size)
+	}
+
+	qr.drawPatterns(version)
+	qr.drawCodewords(qrCodewords(version, []byte(text)))
+
+	// keep the mask with the lowest penalty
+	var best, lowest = 0, -1
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormat(mask)
+		if penalty := qr.penalty(); lowest < 0 || penalty < lowest {
+			best, lowest = mask, penalty
+		}
+		qr.applyMask(mask) // masks are undone applying them again
+	}
+	qr.applyMask(best)
+	qr.drawFormat(best)
+	return qr, nil
+}
+
+// QRCodePNG renders the QR code of the text as a PNG image
+func QRCodePNG(text string) ([]byte, error) {
+	var qr, err = EncodeQR(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		side = (qr.size + QR_QUIET_ZONE*2) * QR_SCALE
+		img  = image.NewGray(image.Rect(0, 0, side, side))
+		buf  bytes.Buffer
+	)
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			var c = color.Gray{Y: 255}
+			if qr.dark(x/QR_SCALE-QR_QUIET_ZONE, y/QR_SCALE-QR_QUIET_ZONE) {
+				c.Y = 0
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dark tells if the module is dark, the ones outside the grid are light
+func (qr QRCode) dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < qr.size && y < qr.size && qr.modules[y][x]
+}
+
+// set colors a module of a pattern
+func (qr *QRCode) set(x, y int, dark bool) {
+	qr.modules[y][x], qr.function[y][x] = dark, true
+}
+
+// drawPatterns draws the finder, timing and alignment patterns and reserves the
+// space for the format and version informations
+func (qr *QRCode) drawPatterns(version int) {
+	for i := 0; i < qr.size; i++ {
+		qr.set(6, i, i%2 == 0)
+		qr.set(i, 6, i%2 == 0)
+	}
+
+	for _, center := range [][2]int{{3, 3}, {qr.size - 4, 3}, {3, qr.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x >= 0 && y >= 0 && x < qr.size && y < qr.size {
+					dist := max(abs(dx), abs(dy))
+					qr.set(x, y, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+
+	var centers = qrVersions[version].alignments
+	for i, cx := range centers {
+		for j, cy := range centers {
+			last := len(centers) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlapping the finder patterns
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	qr.drawFormat(0)
+	if version >= 7 {
+		var rem = version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		var bits = version<<12 | rem
+		for i := 0; i < 18; i++ {
+			a, b := qr.size-11+i%3, i/3
+			qr.set(a, b, bits>>i&1 != 0)
+			qr.set(b, a, bits>>i&1 != 0)
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information for error correction level M
+func (qr *QRCode) drawFormat(mask int) {
+	var (
+		data = mask // the bits of the level M are 00
+		rem  = data
+	)
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	var bits = (data<<10 | rem) ^ 0x5412
+	var bit = func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		qr.set(8, i, bit(i))
+	}
+	qr.set(8, 7, bit(6))
+	qr.set(8, 8, bit(7))
+	qr.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		qr.set(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.set(8, qr.size-15+i, bit(i))
+	}
+	qr.set(8, qr.size-8, true)
+}
+
+// drawCodewords places the bits in the free modules, zigzagging in columns of
+// two from the bottom right corner
+func (qr *QRCode) drawCodewords(codewords []byte) {
+	var i int
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := 0; vert < qr.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert // upward
+				}
+				if !qr.function[y][x] && i < len(codewords)*8 {
+					qr.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by the mask pattern
+func (qr *QRCode) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !qr.function[y][x] {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to read, following the rules of the standard
+func (qr QRCode) penalty() (penalty int) {
+	var dark int
+	for i := 0; i < qr.size; i++ {
+		var row, col = make([]bool, qr.size), make([]bool, qr.size)
+		for j := 0; j < qr.size; j++ {
+			row[j], col[j] = qr.modules[i][j], qr.modules[j][i]
+			if row[j] {
+				dark++
+			}
+		}
+		penalty += qrLinePenalty(row) + qrLinePenalty(col)
+	}
+
+	for y := 0; y < qr.size-1; y++ {
+		for x := 0; x < qr.size-1; x++ {
+			c := qr.modules[y][x]
+			if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+
+	var total = qr.size * qr.size
+	return penalty + ((abs(dark*20-total*10)+total-1)/total-1)*10
+}
+
+// qrLinePenalty scores the runs of modules of the same color and the patterns
+// looking like the finder ones in a row or column
+func qrLinePenalty(line []bool) (penalty int) {
+	var run = 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	var finder = []bool{true, false, true, true, true, false, true}
+	for i := 0; i+len(finder) <= len(line); i++ {
+		var match = true
+		for j, c := range finder {
+			match = match && line[i+j] == c
+		}
+		if match && (qrLight(line, i-4, i) || qrLight(line, i+len(finder), i+len(finder)+4)) {
+			penalty += 40
+		}
+	}
+	return
+}
+
+// qrLight tells if the modules of the line between from and to are all light,
+// the ones outside the line are light too
+func qrLight(line []bool, from, to int) bool {
+	for i := from; i < to; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// qrCountBits is the length of the character count in byte mode
+func qrCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// qrCodewords encodes the data in byte mode, adds the error correction and
+// interleaves the blocks
+func qrCodewords(version int, data []byte) []byte {
+	var (
+		v     = qrVersions[version]
+		bits  []bool
+		write = func(value, length int) {
+			for i := length - 1; i >= 0; i-- {
+				bits = append(bits, value>>i&1 != 0)
+			}
+		}
+	)
+	write(0b0100, 4)
+	write(len(data), qrCountBits(version))
+	for _, b := range data {
+		write(int(b), 8)
+	}
+	write(0, min(4, v.capacity()*8-len(bits)))
+	write(0, (8-len(bits)%8)%8)
+
+	var payload = make([]byte, 0, v.capacity())
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		payload = append(payload, b)
+	}
+	for pad := byte(0xEC); len(payload) < v.capacity(); pad ^= 0xEC ^ 0x11 {
+		payload = append(payload, pad)
+	}
+
+	// the last blocks have one more data codeword when they can not be equal
+	var (
+		short     = v.blocks - v.codewords%v.blocks
+		shortLen  = v.codewords/v.blocks - v.ecc
+		blocks    = make([][]byte, v.blocks)
+		ecc       = make([][]byte, v.blocks)
+		generator = rsGenerator(v.ecc)
+	)
+	for i, start := 0, 0; i < v.blocks; i++ {
+		length := shortLen
+		if i >= short {
+			length++
+		}
+		blocks[i] = payload[start : start+length]
+		ecc[i] = rsRemainder(blocks[i], generator)
+		start += length
+	}
+
+	var result = make([]byte, 0, v.codewords)
+	for i := 0; i <= shortLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecc; i++ {
+		for _, block := range ecc {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// rsGenerator computes the Reed-Solomon generator polynomial of the given degree,
+// without the leading term
+func rsGenerator(degree int) []byte {
+	var (
+		poly = make([]byte, degree)
+		root = byte(1)
+	)
+	poly[degree-1] = 1
+	for i := 0; i < degree; i++ {
+		for j := range poly {
+			poly[j] = gfMultiply(poly[j], root)
+			if j+1 < len(poly) {
+				poly[j] ^= poly[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return poly
+}
+
+// rsRemainder computes the error correction codewords of the data
+func rsRemainder(data, generator []byte) []byte {
+	var result = make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range generator {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies two elements of the Galois field GF(2^8/0x11D)
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+/* --- SCHEDULING --- */
+
+// join makes the invitee join the event of the calendar in the given date
+func join(t *testing.T, fake *FakeTelegram, calendar *Calendar, date FormattedDate) {
+	t.Helper()
+
+	send(t, invitee, fake.Write(invitee, "/start "+calendar.invitation))
+	send(t, invitee, fake.PressData(invitee, fake.Last(invitee.ID), "/join "+calendar.invitation+" "+string(date)))
+	if !calendar.dates[date].hasJoined(invitee.ID) {
+		t.Fatal("the invitee has not joined the event")
+	}
+}
+
+// countSent counts the messages sent to the chat containing the text
+func countSent(fake *FakeTelegram, chatID int64, text string) (count int) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	for _, sent := range fake.Sent {
+		if sent.ChatID == chatID && strings.Contains(sent.Text, text) {
+			count++
+		}
+	}
+	return
+}
+
+func TestReminders(t *testing.T) {
+	fake, clock := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 10)
+		calendar = publish(t, fake, day)
+		week     = strings.SplitN(DEFAULT_LANGUAGE.T("reminder.week"), "%", 2)[0]
+		tomorrow = strings.SplitN(DEFAULT_LANGUAGE.T("reminder.tomorrow"), "%", 2)[0]
+	)
+	join(t, fake, calendar, day.Formatted())
+
+	clock.Advance(3*24*time.Hour - time.Minute)
+	if countSent(fake, invitee.ID, week) != 0 {
+		t.Fatal("the invitee has been reminded more than a week before the event")
+	}
+	clock.Advance(time.Minute)
+	if countSent(fake, invitee.ID, week) != 1 {
+		t.Fatal("the invitee has not been reminded a week before the event")
+	}
+
+	clock.Advance(6 * 24 * time.Hour)
+	if countSent(fake, invitee.ID, tomorrow) != 1 {
+		t.Fatal("the invitee has not been reminded the day before the event")
+	}
+	if countSent(fake, organizer.ID, week)+countSent(fake, organizer.ID, tomorrow) != 0 {
+		t.Fatal("the organizer, who did not join, has been reminded")
+	}
+}
+
+func TestRemindersDisabled(t *testing.T) {
+	fake, clock := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 10)
+		calendar = publish(t, fake, day)
+	)
+	join(t, fake, calendar, day.Formatted())
+	calendar.notification = false
+
+	var sent = len(fake.Sent)
+	clock.Advance(10*24*time.Hour - time.Minute)
+	if len(fake.Sent) != sent {
+		t.Fatalf("%d reminders sent with the notifications disabled", len(fake.Sent)-sent)
+	}
+}
+
+func TestArchiveWhenOccurred(t *testing.T) {
+	fake, clock := newTestBot(t)
+	var (
+		day      = Now().Skip(0, 0, 2)
+		date     = day.Formatted()
+		calendar = publish(t, fake, day)
+	)
+	join(t, fake, calendar, date)
+
+	clock.Advance(2*24*time.Hour - time.Minute)
+	if calendar.dates[date] == nil || calendar.archive[date] != nil {
+		t.Fatal("the event has been archived before it occurred")
+	}
+
+	clock.Advance(time.Minute)
+	if calendar.dates[date] != nil {
+		t.Fatal("the event is still in the calendar after it occurred")
+	}
+	if archived := calendar.archive[date]; archived == nil || !archived.hasJoined(invitee.ID) {
+		t.Fatal("the event has not been archived with its attendees")
+	}
+	if clock.Pending() != 0 {
+		t.Fatalf("%d calls still scheduled for an event that occurred", clock.Pending())
+	}
+}
+
+func TestPastDaysBlocked(t *testing.T) {
+	fake, clock := newTestBot(t)
+	var (
+		day     = Now().Skip(0, 0, 1)
+		blocked = "/alert " + BLOCK.Text(DEFAULT_LANGUAGE.T("alert.day_blocked"))
+	)
+
+	send(t, organizer, fake.Write(organizer, "/publish"))
+	if fake.PressData(organizer, fake.Last(organizer.ID), "/publish "+string(day.Formatted())+" add") == nil {
+		t.Fatal("an upcoming day can't be chosen")
+	}
+	if fake.PressData(organizer, fake.Last(organizer.ID), blocked) == nil {
+		t.Fatal("the days already passed are not blocked")
+	}
+
+	clock.Advance(2 * 24 * time.Hour)
+	send(t, organizer, fake.Write(organizer, "/publish"))
+	if fake.PressData(organizer, fake.Last(organizer.ID), "/publish "+string(day.Formatted())+" add") != nil {
+		t.Fatal("a day already passed can still be chosen")
+	}
+
+	// a selection made before the day passed skips it
+	ToggleDay(organizer.ID, day)
+	ToggleDay(organizer.ID, Now().Skip(0, 0, 1))
+	calendar, added, _, passed := AddSelectedDays(organizer, Now())
+	if len(added) != 1 || len(passed) != 1 || passed[0] != day.Formatted() || calendar.dates[day.Formatted()] != nil {
+		t.Fatalf("the day passed has not been skipped: added %v, passed %v", added, passed)
+	}
+}
+
+func TestUnusedCalendarsRemover(t *testing.T) {
+	_, clock := newTestBot(t)
+	var (
+		unused = AddToCalendar(invitee)
+		used   = AddToCalendar(organizer, Now().Skip(1, 0, 0))
+	)
+
+	Repeat(time.Duration(config.CleanupInterval), UnusedCalendarsRemover(time.Duration(config.UnusedAfter)))
+	clock.Advance(time.Duration(config.UnusedAfter) - time.Hour)
+	if CalendarOf(invitee.ID) != unused {
+		t.Fatal("the calendar has been removed before being unused long enough")
+	}
+
+	clock.Advance(time.Duration(config.CleanupInterval))
+	if CalendarOf(invitee.ID) != nil {
+		t.Fatal("the unused calendar has not been removed")
+	}
+	if CalendarOf(organizer.ID) != used {
+		t.Fatal("the calendar with upcoming events has been removed")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- STATISTICS --- */
+
+// Number of weeks shown by the chart of the joins
+const STATS_WEEKS = 12
+
+// Charts available in the statistics
+const (
+	JOINS_CHART    = "joins"
+	WEEKDAYS_CHART = "weekdays"
+	HOURS_CHART    = "hours"
+)
+
+// CalendarStats are the statistics of a calendar computed from its dates and history
+type CalendarStats struct {
+	Upcoming   int
+	Past       int
+	Weeks      [STATS_WEEKS]Date // start of the weeks of the joins, the last is the current one
+	Joins      [STATS_WEEKS]int  // joins happened in each week
+	Attendance float64           // average attendees of the past events
+	Checked    int               // past events with a check-in
+	NoShows    float64           // share of the attendees of the checked events that did not show up
+	Weekdays   [7]int            // attendees of the events by weekday
+	Hours      [24]int           // attendees of the events by hour of the start
+	Attendees  int               // users that joined at least an event
+	Repeat     int               // users that joined more than an event
+	Visitors   int               // users that opened the invitation links
+	Opens      int               // times the invitation links have been opened
+	Converted  int               // visitors that joined at least an event
+}
+
+// ComputeStats calculates the statistics of the calendar, the weeks of the joins start in the given weekday
+func ComputeStats(c Calendar, first time.Weekday) (stats CalendarStats) {
+	var (
+		joined  = map[int64]int{}
+		week    = Now().WeekStart(first)
+		current = time.Date(week.Year(), week.Month(), week.Day(), 0, 0, 0, 0, config.location)
+	)
+	stats.Upcoming, stats.Past = len(c.dates), len(c.archive)
+
+	for i := range stats.Weeks {
+		stats.Weeks[i] = Parse(current.AddDate(0, 0, -7*(STATS_WEEKS-1-i)))
+	}
+	for _, at := range c.joins {
+		for i := STATS_WEEKS - 1; i >= 0; i-- {
+			if !at.Before(stats.Weeks[i].Time) {
+				stats.Joins[i]++
+				break
+			}
+		}
+	}
+
+	// all-day events are counted once in the weekday they start and not by hour
+	var count = func(date FormattedDate, event *Event) {
+		if parsed, err := date.Parse(); err == nil {
+			stats.Weekdays[parsed.Weekday()] += event.countAttendee()
+			if event.days == 0 {
+				stats.Hours[parsed.Hour()] += event.countAttendee()
+			}
+		}
+		for _, userID := range event.attendee {
+			joined[userID]++
+		}
+	}
+	for date, event := range c.dates {
+		count(date, event)
+	}
+
+	var attendees, checked, noShows int
+	for date, event := range c.archive {
+		count(date, event)
+		attendees += event.countAttendee()
+		if len(event.attended) == 0 {
+			continue
+		}
+		stats.Checked++
+		checked += event.countAttendee()
+		for _, userID := range event.attendee {
+			if !event.hasAttended(userID) {
+				noShows++
+			}
+		}
+	}
+	if stats.Past > 0 {
+		stats.Attendance = float64(attendees) / float64(stats.Past)
+	}
+	if checked > 0 {
+		stats.NoShows = float64(noShows) / float64(checked)
+	}
+
+	stats.Attendees = len(joined)
+	for _, events := range joined {
+		if events > 1 {
+			stats.Repeat++
+		}
+	}
+	for userID, opens := range c.visitors {
+		stats.Visitors++
+		stats.Opens += opens
+		if joined[userID] > 0 {
+			stats.Converted++
+		}
+	}
+	return
+}
+
+// Popular returns the weekday and the hour of the events with more attendees
+func (stats CalendarStats) Popular() (weekday time.Weekday, hour int, ok bool) {
+	for day, attendees := range stats.Weekdays {
+		if attendees > stats.Weekdays[weekday] {
+			weekday = time.Weekday(day)
+		}
+	}
+	for h, attendees := range stats.Hours {
+		if attendees > stats.Hours[hour] {
+			hour = h
+		}
+	}
+	return weekday, hour, stats.Weekdays[weekday] > 0
+}
+
+// buildStatsMessage shows to the organizer the statistics of the calendar
+func buildStatsMessage(lang Language, c Calendar, stats CalendarStats) (text string, kbd [][]tgui.InlineButton) {
+	var totalJoins int
+	for _, joins := range stats.Joins {
+		totalJoins += joins
+	}
+
+	text = lang.T("stats.title", html.EscapeString(c.name)) +
+		lang.T("stats.events", stats.Upcoming, stats.Past) +
+		lang.T("stats.joins", totalJoins, STATS_WEEKS)
+	if stats.Past > 0 {
+		text += lang.T("stats.attendance", stats.Attendance)
+	}
+	if stats.Checked > 0 {
+		text += lang.Plural("stats.no_shows", stats.Checked, stats.NoShows*100)
+	}
+	if stats.Attendees > 0 {
+		text += lang.T("stats.repeat", stats.Repeat, stats.Attendees)
+	}
+	if stats.Visitors > 0 {
+		text += lang.T("stats.conversion", stats.Opens, stats.Visitors, stats.Converted, float64(stats.Converted)*100/float64(stats.Visitors))
+	}
+	if weekday, hour, ok := stats.Popular(); ok {
+		text += lang.T("stats.popular", lang.Weekday(weekday), hour)
+	}
+
+	kbd = [][]tgui.InlineButton{
+		{
+			tgui.InlineCaller(lang.T("btn.chart_joins"), "/stats", JOINS_CHART),
+			tgui.InlineCaller(lang.T("btn.chart_weekdays"), "/stats", WEEKDAYS_CHART),
+			tgui.InlineCaller(lang.T("btn.chart_hours"), "/stats", HOURS_CHART),
+		},
+		{
+			tgui.InlineCaller(REFRESH.Text(lang.T("btn.refresh")), "/stats"),
+			closeButton(lang),
+		},
+	}
+	return
+}
+
+// buildStatsChart renders one of the charts of the statistics as a photo
+func buildStatsChart(lang Language, c Calendar, stats CalendarStats, chart string, first time.Weekday) message.Any {
+	var (
+		labels []string
+		values []int
+	)
+	switch chart {
+	case JOINS_CHART:
+		for i, week := range stats.Weeks {
+			labels = append(labels, week.Format("02/01"))
+			values = append(values, stats.Joins[i])
+		}
+	case WEEKDAYS_CHART:
+		for i := 0; i < 7; i++ {
+			day := (first + time.Weekday(i)) % 7
+			labels = append(labels, strings.ToUpper(short(lang.Weekday(day))))
+			values = append(values, stats.Weekdays[day])
+		}
+	case HOURS_CHART:
+		for hour, attendees := range stats.Hours {
+			labels = append(labels, fmt.Sprint(hour))
+			values = append(values, attendees)
+		}
+	default:
+		return buildErrorMessage(lang, lang.T("error.no_payload"))
+	}
+
+	var image, err = BarChartPNG(labels, values)
+	if err != nil {
+		return buildErrorMessage(lang, err.Error())
+	}
+	return message.Photo{
+		File: echotron.NewInputFileBytes(chart+".png", image),
+		Opts: &echotron.PhotoOptions{
+			Caption:     lang.T("stats.chart."+chart, c.name),
+			ReplyMarkup: tgui.InlineKeyboard(tgui.Wrap(tgui.Wrap(closeButton(lang)))),
+		},
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/* --- STORAGE --- */
+
+// How often the data is saved on the storage file
+const SAVE_INTERVAL = time.Minute
+
+// dataLock must be held by anyone reading or writing the bot's data
+// (organizers, preferences...) outside the handlers, that are already guarded
+var dataLock sync.Mutex
+
+// snapshot is the content of the storage file
+type snapshot struct {
+	Calendars     map[int64]*Calendar     `json:"calendars"`
+	Preferences   map[int64]*Preferences  `json:"preferences"`
+	APITokens     map[string]int64        `json:"api_tokens,omitempty"` // owner of each token, by hash
+	Webhooks      map[int64][]*Webhook    `json:"webhooks,omitempty"`
+	CalDAV        *caldavJSON             `json:"caldav,omitempty"`
+	Cards         map[string]inlineCard   `json:"cards,omitempty"`         // sent using the inline mode
+	Conversations map[int64]*Conversation `json:"conversations,omitempty"` // in progress
+}
+
+// caldavJSON is the CalDAV data saved on the storage file
+type caldavJSON struct {
+	Passwords map[int64]string                  `json:"passwords"` // hashed
+	Objects   map[int64]map[string]caldavObject `json:"objects"`
+}
+
+// SaveData writes all the bot's data on the file at the given path, replacing
+// it only when the new one is completely written
+func SaveData(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	dataLock.Lock()
+	content, err := json.Marshal(snapshot{organizers, preferences, apiTokens, webhooks, &caldavJSON{caldavPasswords, caldavObjects}, inlineCards, conversations})
+	dataLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(content); err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// LoadData reads the bot's data from the file at the given path and schedule
+// again all the reminders and the conversations timeouts, a missing file is not considered an error
+func LoadData(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var data snapshot
+	if err = json.Unmarshal(content, &data); err != nil {
+		return err
+	}
+
+	dataLock.Lock()
+	defer dataLock.Unlock()
+
+	if data.Calendars != nil {
+		organizers = data.Calendars
+	}
+	if data.Preferences != nil {
+		preferences = data.Preferences
+	}
+	if data.APITokens != nil {
+		apiTokens = data.APITokens
+	}
+	if data.Webhooks != nil {
+		webhooks = data.Webhooks
+	}
+	if data.CalDAV != nil && data.CalDAV.Passwords != nil {
+		caldavPasswords = data.CalDAV.Passwords
+	}
+	if data.CalDAV != nil && data.CalDAV.Objects != nil {
+		caldavObjects = data.CalDAV.Objects
+	}
+	if data.Cards != nil {
+		inlineCards = data.Cards
+	}
+	for chatID, conv := range data.Conversations {
+		if _, ok := flows[conv.Flow]; ok && len(conv.Steps) > 0 && clock.Now().Before(conv.Expires) {
+			conversations[chatID] = conv
+			expire(chatID, conv)
+		}
+	}
+	for _, calendar := range organizers {
+		for date := range calendar.dates {
+			if parsed, err := date.Parse(); err == nil {
+				schedule(calendar, parsed)
+			}
+		}
+		for date := range calendar.archive {
+			scheduleFeedback(calendar, date)
+		}
+	}
+	return nil
+}
+
+/* --- SERIALIZATION --- */
+
+type calendarJSON struct {
+	Notification bool                     `json:"notification"`
+	Name         string                   `json:"name"`
+	Description  string                   `json:"description"`
+	Invitation   string                   `json:"invitation"`
+	Capacity     int                      `json:"capacity,omitempty"`
+	Duration     Duration                 `json:"duration,omitempty"`
+	Feed         string                   `json:"feed,omitempty"`
+	LastTimeUsed time.Time                `json:"last_time_used"`
+	Dates        map[FormattedDate]*Event `json:"dates"`
+	Archive      map[FormattedDate]*Event `json:"archive,omitempty"`
+	Joins        []time.Time              `json:"joins,omitempty"`
+	Visitors     map[int64]int            `json:"visitors,omitempty"`
+	Templates    []Template               `json:"templates,omitempty"`
+	Changes      []Change                 `json:"changes,omitempty"`
+}
+
+func (c Calendar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(calendarJSON{
+		Notification: bool(c.notification),
+		Name:         c.name,
+		Description:  c.description,
+		Invitation:   c.invitation,
+		Capacity:     c.capacity,
+		Duration:     Duration(c.duration),
+		Feed:         c.feed,
+		LastTimeUsed: c.lastTimeUsed.Time,
+		Dates:        c.dates,
+		Archive:      c.archive,
+		Joins:        c.joins,
+		Visitors:     c.visitors,
+		Templates:    c.templates,
+		Changes:      c.changes,
+	})
+}
+
+func (c *Calendar) UnmarshalJSON(data []byte) error {
+	var raw calendarJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Calendar{
+		notification: toggler(raw.Notification),
+		name:         raw.Name,
+		description:  raw.Description,
+		invitation:   raw.Invitation,
+		capacity:     raw.Capacity,
+		duration:     time.Duration(raw.Duration),
+		feed:         raw.Feed,
+		lastTimeUsed: Parse(raw.LastTimeUsed),
+		dates:        raw.Dates,
+		archive:      raw.Archive,
+		joins:        raw.Joins,
+		visitors:     raw.Visitors,
+		templates:    raw.Templates,
+		changes:      raw.Changes,
+	}
+	if c.dates == nil {
+		c.dates = make(map[FormattedDate]*Event)
+	}
+	if c.archive == nil {
+		c.archive = make(map[FormattedDate]*Event)
+	}
+	return nil
+}
+
+type eventJSON struct {
+	Days     int                `json:"days,omitempty"`
+	Attendee []int64            `json:"attendee"`
+	Attended []int64            `json:"attended,omitempty"`
+	Feedback map[int64]Feedback `json:"feedback,omitempty"`
+	Asked    bool               `json:"feedback_asked,omitempty"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventJSON{Days: e.days, Attendee: e.attendee, Attended: e.attended, Feedback: e.feedback, Asked: e.asked})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw eventJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*e = Event{days: raw.Days, attendee: raw.Attendee, attended: raw.Attended, feedback: raw.Feedback, asked: raw.Asked}
+	return nil
+}
+
+type preferencesJSON struct {
+	Language    Language      `json:"language"`
+	Chosen      bool          `json:"language_chosen,omitempty"`
+	WeekStart   *time.Weekday `json:"week_start,omitempty"`
+	WeekNumbers bool          `json:"week_numbers,omitempty"`
+	Feed        string        `json:"feed,omitempty"`
+}
+
+func (p Preferences) MarshalJSON() ([]byte, error) {
+	return json.Marshal(preferencesJSON{
+		Language:    p.lang,
+		Chosen:      p.langChosen,
+		WeekStart:   p.weekStart,
+		WeekNumbers: p.weekNumbers,
+		Feed:        p.feed,
+	})
+}
+
+func (p *Preferences) UnmarshalJSON(data []byte) error {
+	var raw preferencesJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = Preferences{
+		lang:        ParseLanguage(string(raw.Language)),
+		langChosen:  raw.Chosen,
+		weekStart:   raw.WeekStart,
+		weekNumbers: raw.WeekNumbers,
+		feed:        raw.Feed,
+	}
+	return nil
+}
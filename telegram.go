@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/robot"
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- TELEGRAM --- */
+
+// Telegram contains all the calls to the Telegram Bot API made by the bot,
+// so that they can be replaced with a fake when there is no live connection
+type Telegram interface {
+	// Send sends a message to the given chat
+	Send(chatID int64, msg message.Any) (*message.UpdateMessage, error)
+	// Edit replaces text and keyboard of the message the callback comes from
+	Edit(callback *message.CallbackQuery, text string, opts *tgui.EditOptions) error
+	// Delete deletes the given message
+	Delete(msg *message.UpdateMessage) error
+	// Answer answers to a callback query, opts can be nil
+	Answer(callback *message.CallbackQuery, opts *echotron.CallbackQueryOptions) error
+	// AnswerInline answers to an inline query with the given results
+	AnswerInline(query *echotron.InlineQuery, results []echotron.InlineQueryResult, opts *echotron.InlineQueryOptions) error
+	// EditInline replaces text and keyboard of a message sent using the inline mode
+	EditInline(inlineMessageID string, text string, opts *tgui.EditOptions) error
+	// Username returns the username of the bot
+	Username() string
+}
+
+// telegram is the Telegram in use, the live API by default
+var telegram Telegram = liveTelegram{}
+
+// liveTelegram uses the real Telegram Bot API
+type liveTelegram struct{}
+
+func (liveTelegram) Send(chatID int64, msg message.Any) (*message.UpdateMessage, error) {
+	return msg.Send(chatID)
+}
+
+func (liveTelegram) Edit(callback *message.CallbackQuery, text string, opts *tgui.EditOptions) error {
+	if callback.Message == nil {
+		return liveTelegram{}.EditInline(callback.InlineMessageID, text, opts)
+	}
+	return callback.EditText(text, opts)
+}
+
+func (liveTelegram) EditInline(inlineMessageID string, text string, opts *tgui.EditOptions) error {
+	_, err := message.API().EditMessageText(text, echotron.NewInlineMessageID(inlineMessageID), opts)
+	return err
+}
+
+func (liveTelegram) Delete(msg *message.UpdateMessage) error {
+	if msg == nil {
+		return errors.New("missing message")
+	}
+	return msg.Delete()
+}
+
+func (liveTelegram) Answer(callback *message.CallbackQuery, opts *echotron.CallbackQueryOptions) error {
+	return callback.Answer(opts)
+}
+
+func (liveTelegram) AnswerInline(query *echotron.InlineQuery, results []echotron.InlineQueryResult, opts *echotron.InlineQueryOptions) error {
+	_, err := message.API().AnswerInlineQuery(query.ID, results, opts)
+	return err
+}
+
+// botUsername caches the username of the bot once retrieved
+var botUsername struct {
+	sync.Mutex
+	value string
+}
+
+func (liveTelegram) Username() string {
+	botUsername.Lock()
+	defer botUsername.Unlock()
+
+	if botUsername.value == "" {
+		var res, err = message.API().GetMe()
+		if err == nil && res.Result != nil {
+			botUsername.value = res.Result.Username
+		}
+	}
+	return botUsername.value
+}
+
+// showMessage edits the message in case of callback query, otherwise sends a new one
+// to the chat of the incoming message (like tgui.ShowMessage but using telegram)
+func showMessage(update *message.Update, text string, opts *tgui.EditOptions) error {
+	if callback := update.CallbackQuery; callback != nil {
+		if err := telegram.Edit(callback, text, opts); err != nil {
+			return err
+		}
+		return telegram.Answer(callback, nil)
+	}
+
+	if original := update.FromMessage(); original != nil && original.Chat != nil {
+		_, err := telegram.Send(original.Chat.ID, message.Text{Text: text, Opts: tgui.ToMessageOptions(opts)})
+		return err
+	}
+	return errors.New("invalid given update")
+}
+
+/* --- DISPATCHER --- */
+
+// POLL_TIMEOUT is the max number of seconds each request of updates is kept open
+const POLL_TIMEOUT = 120
+
+var triggerRgx = regexp.MustCompile(`^/\w+`)
+
+// Poll receives the updates from Telegram and handles each one with Dispatch.
+// It's used instead of robot.Start because the echotron dispatcher can not handle
+// the callback queries of the messages sent in inline mode, as they have no chat
+func Poll() error {
+	var api = message.API()
+	if _, err := api.DeleteWebhook(true); err != nil {
+		return err
+	}
+
+	var opts = echotron.UpdateOptions{Timeout: POLL_TIMEOUT}
+	for {
+		res, err := api.GetUpdates(&opts)
+		if err != nil {
+			return err
+		}
+		for _, original := range res.Result {
+			opts.Offset = original.ID + 1
+			update := message.CastUpdate(original)
+			if chatID := senderOf(update); chatID != 0 {
+				go Dispatch(chatID, update)
+			}
+		}
+	}
+}
+
+// senderOf returns the ID of the chat the update comes from, for updates not
+// coming from a chat it's the one of the user, 0 when unknown
+func senderOf(update *message.Update) int64 {
+	switch {
+	case update.Message != nil && update.Message.Chat != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil && update.CallbackQuery.Message.Chat != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.From != nil:
+		return update.CallbackQuery.From.ID
+	case update.InlineQuery != nil && update.InlineQuery.From != nil:
+		return update.InlineQuery.From.ID
+	case update.ChosenInlineResult != nil && update.ChosenInlineResult.From != nil:
+		return update.ChosenInlineResult.From.ID
+	}
+	return 0
+}
+
+// Dispatch handles an update sent by the given chat using the bot's commands in the
+// same way robot.Start does
+func Dispatch(chatID int64, update *message.Update) {
+	var (
+		trigger string
+		filter  message.UpdateType
+	)
+
+	switch {
+	case update.Message != nil:
+		trigger, filter = triggerRgx.FindString(update.Message.Text), message.MESSAGE
+	case update.CallbackQuery != nil:
+		trigger, filter = triggerRgx.FindString(update.CallbackQuery.Data), message.CALLBACK_QUERY
+	case update.InlineQuery != nil:
+		filter = message.INLINE_QUERY
+	case update.ChosenInlineResult != nil:
+		filter = message.CHOSEN_INLINE_RESULT
+	default:
+		return
+	}
+
+	for _, command := range commands {
+		if command.ReplyAt&filter != 0 && command.Trigger == trigger {
+			if msg := command.CallFunc(&robot.Bot{ChatID: chatID}, update); msg != nil {
+				telegram.Send(chatID, msg)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/DazFather/parrbot/message"
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- FAKE TELEGRAM --- */
+
+// FakeTelegram is an in-memory Telegram that records everything the bot does
+// instead of calling the API, use it with Dispatch to drive the bot offline
+type FakeTelegram struct {
+	sync.Mutex
+	BotUsername string
+	Chats       map[int64][]*FakeMessage     // messages currently visible in each chat
+	Sent        []FakeMessage                // every message sent, in order
+	Edited      []FakeMessage                // every message edited, with the new content
+	Deleted     []FakeMessage                // every message deleted
+	Answers     []string                     // text of every answer to callback queries
+	Results     []echotron.InlineQueryResult // results of the last answered inline query
+	Inline      map[string]*FakeMessage      // messages sent using the inline mode, by inline message ID
+
+	lastID int
+}
+
+// FakeMessage is a message shown by the FakeTelegram
+type FakeMessage struct {
+	ChatID   int64
+	ID       int
+	InlineID string // set only for the messages sent using the inline mode
+	Text     string
+	Keyboard [][]tgui.InlineButton
+}
+
+// NewFakeTelegram creates an empty FakeTelegram and starts using it instead of the live API
+func NewFakeTelegram(username string) *FakeTelegram {
+	fake := &FakeTelegram{BotUsername: username, Chats: map[int64][]*FakeMessage{}, Inline: map[string]*FakeMessage{}}
+	telegram = fake
+	return fake
+}
+
+func (f *FakeTelegram) Send(chatID int64, msg message.Any) (*message.UpdateMessage, error) {
+	var sent = FakeMessage{ChatID: chatID}
+	switch m := msg.(type) {
+	case message.Text:
+		sent.Text = m.Text
+		if m.Opts != nil {
+			sent.Keyboard = keyboardOf(m.Opts.ReplyMarkup)
+		}
+	case message.Photo:
+		if m.Opts != nil {
+			sent.Text = m.Opts.Caption
+			sent.Keyboard = keyboardOf(m.Opts.ReplyMarkup)
+		}
+	default:
+		return nil, errors.New("message type not supported by the fake")
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.lastID++
+	sent.ID = f.lastID
+	f.Sent = append(f.Sent, sent)
+	f.Chats[chatID] = append(f.Chats[chatID], &sent)
+	return sent.message(), nil
+}
+
+func (f *FakeTelegram) Edit(callback *message.CallbackQuery, text string, opts *tgui.EditOptions) error {
+	if callback.Message == nil {
+		return f.EditInline(callback.InlineMessageID, text, opts)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	return f.edit(f.find(callback.Message), text, opts)
+}
+
+func (f *FakeTelegram) EditInline(inlineMessageID string, text string, opts *tgui.EditOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	return f.edit(f.Inline[inlineMessageID], text, opts)
+}
+
+// edit replaces the content of a shown message
+func (f *FakeTelegram) edit(shown *FakeMessage, text string, opts *tgui.EditOptions) error {
+	if shown == nil {
+		return errors.New("message to edit not found")
+	}
+	shown.Text, shown.Keyboard = text, nil
+	if opts != nil {
+		shown.Keyboard = opts.ReplyMarkup.InlineKeyboard
+	}
+	f.Edited = append(f.Edited, *shown)
+	return nil
+}
+
+func (f *FakeTelegram) Delete(msg *message.UpdateMessage) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if msg == nil || msg.Chat == nil {
+		return errors.New("missing message")
+	}
+	var chat = f.Chats[msg.Chat.ID]
+	for i, shown := range chat {
+		if shown.ID == msg.ID {
+			f.Deleted = append(f.Deleted, *shown)
+			f.Chats[msg.Chat.ID] = append(chat[:i:i], chat[i+1:]...)
+			return nil
+		}
+	}
+	// messages sent by the users are not tracked but can be deleted
+	f.Deleted = append(f.Deleted, FakeMessage{ChatID: msg.Chat.ID, ID: msg.ID, Text: msg.Text})
+	return nil
+}
+
+func (f *FakeTelegram) Answer(callback *message.CallbackQuery, opts *echotron.CallbackQueryOptions) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if opts != nil && opts.Text != "" {
+		f.Answers = append(f.Answers, opts.Text)
+	}
+	return nil
+}
+
+func (f *FakeTelegram) AnswerInline(query *echotron.InlineQuery, results []echotron.InlineQueryResult, opts *echotron.InlineQueryOptions) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.Results = results
+	return nil
+}
+
+func (f *FakeTelegram) Username() string {
+	return f.BotUsername
+}
+
+// Write simulates the user writing the given text to the bot
+func (f *FakeTelegram) Write(user echotron.User, text string) *message.Update {
+	f.Lock()
+	f.lastID++
+	var id = f.lastID
+	f.Unlock()
+
+	return &message.Update{Message: &message.UpdateMessage{
+		ID:   id,
+		From: &user,
+		Chat: &echotron.Chat{ID: user.ID, Type: "private"},
+		Text: text,
+	}}
+}
+
+// Query simulates the user typing the username of the bot followed by the given text
+func (f *FakeTelegram) Query(user echotron.User, text string) *message.Update {
+	return &message.Update{InlineQuery: &echotron.InlineQuery{ID: "query", From: &user, Query: text}}
+}
+
+// Choose simulates the user sending the article with the given ID among the results
+// of the last inline query, it returns nil if there is no such article
+func (f *FakeTelegram) Choose(user echotron.User, resultID string) (*message.Update, *FakeMessage) {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, result := range f.Results {
+		article, ok := result.(echotron.InlineQueryResultArticle)
+		if !ok || article.ID != resultID {
+			continue
+		}
+
+		f.lastID++
+		shown := &FakeMessage{ID: f.lastID, InlineID: fmt.Sprint("inline", f.lastID), Keyboard: keyboardOf(article.ReplyMarkup)}
+		if content, ok := article.InputMessageContent.(echotron.InputTextMessageContent); ok {
+			shown.Text = content.MessageText
+		}
+		f.Sent = append(f.Sent, *shown)
+		f.Inline[shown.InlineID] = shown
+
+		var sent = *shown
+		return &message.Update{ChosenInlineResult: &echotron.ChosenInlineResult{
+			ResultID:        resultID,
+			From:            &user,
+			InlineMessageID: shown.InlineID,
+		}}, &sent
+	}
+	return nil, nil
+}
+
+// Press simulates the user pressing the button of a message containing the given
+// caption, nil is returned if there is no such button
+func (f *FakeTelegram) Press(user echotron.User, msg *FakeMessage, caption string) *message.Update {
+	return pressWhere(user, msg, func(button tgui.InlineButton) bool {
+		return strings.Contains(button.Text, caption)
+	})
+}
+
+// PressData simulates the user pressing the button of a message having exactly the
+// given callback data, nil is returned if there is no such button
+func (f *FakeTelegram) PressData(user echotron.User, msg *FakeMessage, data string) *message.Update {
+	return pressWhere(user, msg, func(button tgui.InlineButton) bool {
+		return button.CallbackData == data
+	})
+}
+
+// pressWhere simulates the user pressing the first button of a message that matches
+func pressWhere(user echotron.User, msg *FakeMessage, match func(tgui.InlineButton) bool) *message.Update {
+	if msg == nil {
+		return nil
+	}
+	for _, row := range msg.Keyboard {
+		for _, button := range row {
+			if button.CallbackData != "" && match(button) {
+				callback := &message.CallbackQuery{ID: button.CallbackData, From: &user, Data: button.CallbackData}
+				if msg.InlineID != "" {
+					callback.InlineMessageID = msg.InlineID
+				} else {
+					callback.Message = msg.message()
+				}
+				return &message.Update{CallbackQuery: callback}
+			}
+		}
+	}
+	return nil
+}
+
+// Last returns the most recent message currently visible in the chat
+func (f *FakeTelegram) Last(chatID int64) *FakeMessage {
+	f.Lock()
+	defer f.Unlock()
+
+	if chat := f.Chats[chatID]; len(chat) > 0 {
+		shown := *chat[len(chat)-1]
+		return &shown
+	}
+	return nil
+}
+
+// find returns the visible message matching the given one
+func (f *FakeTelegram) find(msg *message.UpdateMessage) *FakeMessage {
+	if msg == nil || msg.Chat == nil {
+		return nil
+	}
+	for _, shown := range f.Chats[msg.Chat.ID] {
+		if shown.ID == msg.ID {
+			return shown
+		}
+	}
+	return nil
+}
+
+// message converts to the format of the messages contained in the updates
+func (m FakeMessage) message() *message.UpdateMessage {
+	return &message.UpdateMessage{
+		ID:             m.ID,
+		Chat:           &echotron.Chat{ID: m.ChatID, Type: "private"},
+		Text:           m.Text,
+		InlineKeyboard: &echotron.InlineKeyboardMarkup{InlineKeyboard: m.Keyboard},
+	}
+}
+
+// keyboardOf grabs the inline keyboard from a reply markup
+func keyboardOf(markup echotron.ReplyMarkup) [][]tgui.InlineButton {
+	switch kbd := markup.(type) {
+	case echotron.InlineKeyboardMarkup:
+		return kbd.InlineKeyboard
+	case *echotron.InlineKeyboardMarkup:
+		if kbd != nil {
+			return kbd.InlineKeyboard
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DazFather/parrbot/tgui"
+	"github.com/NicoNex/echotron/v3"
+)
+
+/* --- TEMPLATES --- */
+
+const (
+	MAX_TEMPLATES        = 10  // max number of templates of a calendar
+	MAX_TEMPLATE_NAME    = 32  // max length of the name of a template
+	TEMPLATE_PREFIX      = "t" // of the sources of the copies referring to a template
+	TEMPLATE_TIME_FORMAT = "15:04"
+)
+
+// Payloads of /publish used to copy an event or a template to other days
+const (
+	COPY_VIEW = "copy"  // month grid where the days of the copies are selected
+	COPY_ADD  = "paste" // adds a copy in the selected day
+)
+
+// Template is the shape of an event that can be added to other days: the time
+// when it starts or, for the all-day events, the days it covers
+type Template struct {
+	Name string `json:"name,omitempty"`
+	Time string `json:"time,omitempty"` // empty for the all-day events
+	Days int    `json:"days,omitempty"`
+}
+
+// TemplateOf returns the shape of the event in the given date of the calendar
+func TemplateOf(c Calendar, date FormattedDate) (template Template, ok bool) {
+	var parsed, err = date.Parse()
+	if err != nil || c.event(date) == nil {
+		return
+	}
+	if template.Days = c.Days(date); template.Days == 0 {
+		template.Time = parsed.Format(TEMPLATE_TIME_FORMAT)
+	}
+	return template, true
+}
+
+// Label returns the name of the template or, if not set, a description of its shape
+func (t Template) Label(lang Language) string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.Days > 0:
+		return lang.Plural("template.all_day", t.Days)
+	default:
+		return lang.T("template.timed", t.Time)
+	}
+}
+
+// Date returns when the template starts in the given day
+func (t Template) Date(day Date) (Date, error) {
+	if t.Days > 0 {
+		return day.DayStart(), nil
+	}
+	at, err := time.Parse(TEMPLATE_TIME_FORMAT, t.Time)
+	if err != nil {
+		return day, err
+	}
+	return Parse(time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location())), nil
+}
+
+// saveTemplate adds the template to the calendar, if it has not an equal one yet
+func (c *Calendar) saveTemplate(template Template) (index int, err error) {
+	for i, saved := range c.templates {
+		if saved.Time == template.Time && saved.Days == template.Days {
+			return i, nil
+		}
+	}
+	if len(c.templates) >= MAX_TEMPLATES {
+		return -1, TOO_MANY_TEMPLATES
+	}
+	c.templates = append(c.templates, template)
+	return len(c.templates) - 1, nil
+}
+
+// renameTemplate changes the name of a template, an empty one restores the default label
+func (c *Calendar) renameTemplate(index int, name string) error {
+	if index < 0 || index >= len(c.templates) {
+		return INVALID_TEMPLATE
+	}
+	if name = strings.TrimSpace(name); len([]rune(name)) > MAX_TEMPLATE_NAME {
+		return INVALID_VALUE
+	}
+	c.templates[index].Name = name
+	return nil
+}
+
+func (c *Calendar) removeTemplate(index int) bool {
+	if index < 0 || index >= len(c.templates) {
+		return false
+	}
+	c.templates = append(c.templates[:index:index], c.templates[index+1:]...)
+	return true
+}
+
+// templateSource is the source of the copies referring to the template at the given index
+func templateSource(index int) string {
+	return TEMPLATE_PREFIX + strconv.Itoa(index)
+}
+
+// ResolveTemplate returns the shape of the source of some copies: one of the
+// templates of the calendar or the event in the given date
+func ResolveTemplate(c Calendar, source string) (Template, bool) {
+	if strings.HasPrefix(source, TEMPLATE_PREFIX) {
+		index, err := strconv.Atoi(strings.TrimPrefix(source, TEMPLATE_PREFIX))
+		if err != nil || index < 0 || index >= len(c.templates) {
+			return Template{}, false
+		}
+		return c.templates[index], true
+	}
+	return TemplateOf(c, FormattedDate(source))
+}
+
+// ApplyTemplate adds to the calendar of the user an event shaped as the template
+// in the given day, added is false when the calendar already has one in that date
+func ApplyTemplate(user echotron.User, template Template, day Date) (calendar *Calendar, date FormattedDate, added bool) {
+	start, err := template.Date(day)
+	if err != nil {
+		return CalendarOf(user.ID), "", false
+	}
+	if date = start.Formatted(); template.Days > 0 {
+		calendar, added = AddSpanToCalendar(user, start, start.Skip(0, 0, template.Days-1))
+		return
+	}
+
+	added = CalendarOf(user.ID) == nil || CalendarOf(user.ID).dates[date] == nil
+	return AddToCalendar(user, start), date, added
+}
+
+// buildCopyMessage is the month grid used to select the days where the source,
+// an event or a template, is copied
+func buildCopyMessage(lang Language, c Calendar, userID int64, date Date, source string) (text string, kbd [][]tgui.InlineButton) {
+	var (
+		template, _ = ResolveTemplate(c, source)
+		month       = lang.Format(date.Time, "January 2006")
+		now         = Now()
+		blocked     = alertCaller(BLOCK, "", lang.T("alert.day_blocked"))
+	)
+	text = lang.T("copy.select", template.Label(lang), month)
+
+	date = date.MonthStart()
+	keyboard := genMonthGrid(lang, userID, date, []tgui.InlineButton{
+		tgui.InlineCaller("⏮", "/publish", string(date.Skip(0, -1, 0).Formatted()), COPY_VIEW, source),
+		alertCaller("", month, month),
+		tgui.InlineCaller("⏭", "/publish", string(date.Skip(0, 1, 0).Formatted()), COPY_VIEW, source),
+	}, func(day Date) tgui.InlineButton {
+		var label = fmt.Sprint(day.Day())
+		if len(c.DatesOn(day)) > 0 {
+			label += string(EVENT)
+		}
+
+		start, err := template.Date(day)
+		switch {
+		case err != nil, template.Days == 0 && start.IsBefore(now), day.DayStart().IsBefore(now.DayStart()):
+			return blocked
+		case c.dates[start.Formatted()] != nil:
+			return alertCaller(DONE, label, lang.T("alert.copy_exists"))
+		default:
+			return tgui.InlineCaller(label, "/publish", string(day.Formatted()), COPY_ADD, source)
+		}
+	})
+
+	var row = []tgui.InlineButton{cancelButton(lang)}
+	if !strings.HasPrefix(source, TEMPLATE_PREFIX) {
+		row = append([]tgui.InlineButton{tgui.InlineCaller("💾 "+lang.T("btn.save_template"), "/template", "save", source)}, row...)
+	}
+	return "⧉ " + text, append(keyboard, row)
+}
+
+// buildTemplatesMessage shows the templates of the calendar with the buttons to use or delete them
+func buildTemplatesMessage(lang Language, c Calendar) (text string, kbd [][]tgui.InlineButton) {
+	text = lang.T("template.title", html.EscapeString(c.name))
+	if len(c.templates) == 0 {
+		return text + lang.T("template.empty"), tgui.Wrap(tgui.Wrap(closeButton(lang)))
+	}
+	for i, template := range c.templates {
+		text += fmt.Sprint("\n", i+1, ". ", template.Label(lang))
+		kbd = append(kbd, []tgui.InlineButton{
+			tgui.InlineCaller("⧉ "+template.Label(lang), "/publish", string(Now().Formatted()), COPY_VIEW, templateSource(i)),
+			tgui.InlineCaller("🗑", "/template", "delete", strconv.Itoa(i)),
+		})
+	}
+	text += lang.T("template.help")
+	return text, append(kbd, tgui.Wrap(closeButton(lang)))
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/* --- WEBHOOKS --- */
+
+const (
+	MAX_WEBHOOKS          = 5                // max number of webhooks of a calendar
+	WEBHOOK_MAX_ATTEMPTS  = 6                // max number of times a delivery is tried
+	WEBHOOK_FIRST_BACKOFF = time.Second * 30 // wait before the first retry, doubled at every attempt
+	WEBHOOK_TIMEOUT       = time.Second * 10 // max time waited for the response
+	WEBHOOK_LOG_SIZE      = 20               // number of deliveries kept in the log of each calendar
+)
+
+// Type of the events sent to the webhooks
+const (
+	HOOK_JOINED        = "event.joined"
+	HOOK_LEFT          = "event.left"
+	HOOK_DATE_ADDED    = "date.added"
+	HOOK_DATE_REMOVED  = "date.removed"
+	HOOK_DATE_ARCHIVED = "date.archived"
+	HOOK_EDITED        = "calendar.edited"
+	HOOK_REMINDED      = "reminder.sent"
+	HOOK_ATTENDED      = "event.attended"
+	HOOK_RATED         = "event.rated"
+	HOOK_TEST          = "webhook.test"
+)
+
+// Webhook is an URL that receives the events of a calendar signed with its secret
+type Webhook struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// webhooks are the webhooks registered by each organizer
+var webhooks = map[int64][]*Webhook{}
+
+// HookEvent is the JSON body sent to the webhooks
+type HookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Calendar  string      `json:"calendar"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery is an attempt of sending an event to a webhook
+type Delivery struct {
+	Time    time.Time
+	Webhook string // ID of the webhook
+	Event   string // type of the event
+	Attempt int
+	Status  int    // HTTP status code of the response, 0 if none
+	Error   string // empty if successful
+}
+
+// deliveries is the log of the last deliveries of each organizer's webhooks, it
+// has its own lock because deliveries happen outside of the handlers
+var deliveries = struct {
+	sync.Mutex
+	log map[int64][]Delivery
+}{log: map[int64][]Delivery{}}
+
+// hookClient is the HTTP client used for the deliveries, it connects only to
+// public addresses, checked again at every dial so that the DNS can't be used
+// to point a registered webhook (or its redirects) to the internal network
+var hookClient = &http.Client{
+	Timeout: WEBHOOK_TIMEOUT,
+	Transport: &http.Transport{
+		DialContext:         dialPublic,
+		TLSHandshakeTimeout: WEBHOOK_TIMEOUT,
+	},
+}
+
+// dialPublic connects to the first public address the host resolves to, refusing
+// the connection if any of them is not public
+func dialPublic(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := resolvePublic(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// resolvePublic looks up the addresses of the host, failing with PRIVATE_WEBHOOK
+// if any of them is not public
+func resolvePublic(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips = make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return nil, PRIVATE_WEBHOOK
+		}
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// isPublicIP tells if the address is reachable on the internet, excluding the
+// loopback, private (RFC 1918 and unique local), link-local (metadata services
+// included), shared, unspecified and multicast ones
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false // 100.64.0.0/10, shared by carrier-grade NATs
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// AddWebhook registers a new webhook for the calendar of a user, its host must
+// resolve only to public addresses
+func AddWebhook(userID int64, rawURL string) (*Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, INVALID_WEBHOOK
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), WEBHOOK_TIMEOUT)
+	defer cancel()
+	if _, err = resolvePublic(ctx, parsed.Hostname()); err == PRIVATE_WEBHOOK {
+		return nil, PRIVATE_WEBHOOK
+	} else if err != nil {
+		return nil, INVALID_WEBHOOK
+	}
+	if len(webhooks[userID]) >= MAX_WEBHOOKS {
+		return nil, TOO_MANY_WEBHOOKS
+	}
+
+	hook := &Webhook{ID: randomHex(4), URL: rawURL, Secret: randomHex(16)}
+	webhooks[userID] = append(webhooks[userID], hook)
+	return hook, nil
+}
+
+// RemoveWebhook deletes the webhook with the given ID of the calendar of a user
+func RemoveWebhook(userID int64, hookID string) bool {
+	for i, hook := range webhooks[userID] {
+		if hook.ID == hookID {
+			webhooks[userID] = append(webhooks[userID][:i:i], webhooks[userID][i+1:]...)
+			if len(webhooks[userID]) == 0 {
+				delete(webhooks, userID)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookOf returns the webhook with the given ID of the calendar of a user
+func WebhookOf(userID int64, hookID string) *Webhook {
+	for _, hook := range webhooks[userID] {
+		if hook.ID == hookID {
+			return hook
+		}
+	}
+	return nil
+}
+
+// DeliveriesOf returns the last deliveries made for the calendar of a user, most recent first
+func DeliveriesOf(userID int64) []Delivery {
+	deliveries.Lock()
+	defer deliveries.Unlock()
+
+	var log = deliveries.log[userID]
+	var reversed = make([]Delivery, len(log))
+	for i, delivery := range log {
+		reversed[len(log)-1-i] = delivery
+	}
+	return reversed
+}
+
+// Emit sends an event of the calendar to all its webhooks
+func Emit(calendar *Calendar, eventType string, data interface{}) {
+	if calendar == nil {
+		return
+	}
+	if ownerID := retreiveOwner(calendar.invitation); ownerID != nil {
+		for _, hook := range webhooks[*ownerID] {
+			EmitTo(*ownerID, *hook, calendar.invitation, eventType, data)
+		}
+	}
+}
+
+// EmitTo sends an event to a single webhook without blocking, retrying with
+// an exponential backoff when it fails until the webhook is removed
+func EmitTo(ownerID int64, hook Webhook, invitation, eventType string, data interface{}) {
+	body, err := json.Marshal(HookEvent{
+		ID:        randomHex(8),
+		Type:      eventType,
+		Calendar:  invitation,
+		CreatedAt: clock.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		logDelivery(ownerID, Delivery{Time: clock.Now(), Webhook: hook.ID, Event: eventType, Error: err.Error()})
+		return
+	}
+
+	var attempt func(n int, backoff time.Duration)
+	attempt = func(n int, backoff time.Duration) {
+		status, err := deliver(hook, eventType, body)
+		delivery := Delivery{Time: clock.Now(), Webhook: hook.ID, Event: eventType, Attempt: n, Status: status}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		logDelivery(ownerID, delivery)
+
+		if err != nil && n < WEBHOOK_MAX_ATTEMPTS {
+			clock.AfterFunc(backoff, func() {
+				dataLock.Lock()
+				defer dataLock.Unlock()
+				if WebhookOf(ownerID, hook.ID) != nil {
+					go attempt(n+1, backoff*2)
+				}
+			})
+		}
+	}
+	go attempt(1, WEBHOOK_FIRST_BACKOFF)
+}
+
+// deliver makes the HTTP request to the webhook, signing the body with its secret
+func deliver(hook Webhook, eventType string, body []byte) (status int, err error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Calendaggerbill-Event", eventType)
+	req.Header.Set("X-Calendaggerbill-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	res, err := hookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// logDelivery adds the delivery to the log of the organizer, dropping the oldest ones
+func logDelivery(ownerID int64, delivery Delivery) {
+	deliveries.Lock()
+	defer deliveries.Unlock()
+
+	var log = append(deliveries.log[ownerID], delivery)
+	if len(log) > WEBHOOK_LOG_SIZE {
+		log = log[len(log)-WEBHOOK_LOG_SIZE:]
+	}
+	deliveries.log[ownerID] = log
+}
+
+// hookDate is the way dates are written in the events
+func hookDate(date FormattedDate) string {
+	if parsed, err := date.Parse(); err == nil {
+		return parsed.Format(API_DATE_FORMAT)
+	}
+	return string(date)
+}
+
+// randomHex generates a random hexadecimal string of n bytes
+func randomHex(n int) string {
+	var raw = make([]byte, n)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
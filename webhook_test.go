@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+/* --- WEBHOOKS --- */
+
+func TestIsPublicIP(t *testing.T) {
+	for _, test := range []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},        // loopback
+		{"127.255.255.254", false},  // loopback
+		{"::1", false},              // loopback
+		{"10.0.0.1", false},         // RFC 1918
+		{"172.16.0.1", false},       // RFC 1918
+		{"172.31.255.255", false},   // RFC 1918
+		{"172.32.0.1", true},        // just outside of RFC 1918
+		{"192.168.1.1", false},      // RFC 1918
+		{"169.254.169.254", false},  // link-local, metadata services
+		{"fe80::1", false},          // link-local
+		{"100.64.0.1", false},       // CGNAT
+		{"100.127.255.255", false},  // CGNAT
+		{"100.128.0.1", true},       // just outside of CGNAT
+		{"fd00:ec2::254", false},    // IPv6 ULA, metadata services
+		{"fc00::1", false},          // IPv6 ULA
+		{"::ffff:127.0.0.1", false}, // IPv4-mapped loopback
+		{"::ffff:10.0.0.1", false},  // IPv4-mapped RFC 1918
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:93.184.216.34", true},
+		{"0.0.0.0", false},   // unspecified
+		{"::", false},        // unspecified
+		{"224.0.0.1", false}, // multicast
+		{"ff02::1", false},   // multicast
+	} {
+		if public := isPublicIP(net.ParseIP(test.ip)); public != test.public {
+			t.Errorf("isPublicIP(%s) = %v, expected %v", test.ip, public, test.public)
+		}
+	}
+}
+
+func TestAddPrivateWebhook(t *testing.T) {
+	newTestBot(t)
+
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/hook",
+		"http://[::ffff:192.168.0.1]/hook",
+	} {
+		if _, err := AddWebhook(organizer.ID, rawURL); err != PRIVATE_WEBHOOK {
+			t.Errorf("the webhook %s has been registered: %v", rawURL, err)
+		}
+	}
+	if _, err := AddWebhook(organizer.ID, "ftp://93.184.216.34/hook"); err != INVALID_WEBHOOK {
+		t.Errorf("a webhook with an unsupported scheme has been registered: %v", err)
+	}
+	if len(webhooks[organizer.ID]) != 0 {
+		t.Fatalf("%d webhooks registered", len(webhooks[organizer.ID]))
+	}
+}
+
+func TestDeliverToPrivateAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// a webhook registered before its host pointed to the internal network
+	_, err := deliver(Webhook{ID: "hook", URL: server.URL, Secret: "secret"}, HOOK_TEST, []byte("{}"))
+	if !errors.Is(err, PRIVATE_WEBHOOK) || called {
+		t.Fatalf("the delivery reached a loopback address: %v", err)
+	}
+}
+
+// useHookServer makes the deliveries reach the handler on a local server, that
+// would be refused otherwise, until the test ends
+func useHookServer(t *testing.T, handler http.HandlerFunc) Webhook {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	previous := hookClient
+	hookClient = server.Client()
+	t.Cleanup(func() {
+		hookClient = previous
+		server.Close()
+	})
+	return Webhook{ID: "hook", URL: server.URL, Secret: "secret"}
+}
+
+func TestDeliverSignature(t *testing.T) {
+	var (
+		received  []byte
+		signature string
+		event     string
+	)
+	hook := useHookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Calendaggerbill-Signature")
+		event = r.Header.Get("X-Calendaggerbill-Event")
+	})
+
+	var body = []byte(`{"type":"webhook.test"}`)
+	if status, err := deliver(hook, HOOK_TEST, body); err != nil || status != http.StatusOK {
+		t.Fatalf("delivery failed with status %d: %v", status, err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(received)
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); !hmac.Equal([]byte(signature), []byte(expected)) {
+		t.Fatalf("invalid signature %q, expected %q", signature, expected)
+	}
+	if string(received) != string(body) || event != HOOK_TEST {
+		t.Fatalf("unexpected delivery of %q: %s", event, received)
+	}
+
+	// a different secret can't verify the signature
+	mac = hmac.New(sha256.New, []byte("other secret"))
+	mac.Write(received)
+	if hmac.Equal([]byte(signature), []byte("sha256="+hex.EncodeToString(mac.Sum(nil)))) {
+		t.Fatal("the signature is verified by a different secret")
+	}
+}
+
+// waitDeliveries waits until the given number of deliveries is logged for the organizer
+func waitDeliveries(t *testing.T, ownerID int64, count int) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if logged := len(DeliveriesOf(ownerID)); logged >= count {
+			if logged > count {
+				t.Fatalf("%d deliveries logged, expected %d", logged, count)
+			}
+			return
+		}
+	}
+	t.Fatalf("%d deliveries logged, expected %d", len(DeliveriesOf(ownerID)), count)
+}
+
+// waitRetry waits until the next attempt of a failed delivery is scheduled
+func waitRetry(t *testing.T, clock *FakeClock) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if clock.Pending() > 0 {
+			return
+		}
+	}
+	t.Fatal("no retry scheduled")
+}
+
+func TestRetryDeliveries(t *testing.T) {
+	_, clock := newTestBot(t)
+	deliveries.log = map[int64][]Delivery{}
+
+	hook := useHookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	webhooks[organizer.ID] = []*Webhook{&hook}
+
+	EmitTo(organizer.ID, hook, "invitation", HOOK_TEST, nil)
+	waitDeliveries(t, organizer.ID, 1)
+	waitRetry(t, clock)
+	clock.Advance(WEBHOOK_FIRST_BACKOFF)
+	waitDeliveries(t, organizer.ID, 2)
+	waitRetry(t, clock)
+	if last := DeliveriesOf(organizer.ID)[0]; last.Attempt != 2 || last.Status != http.StatusInternalServerError {
+		t.Fatalf("unexpected retry: %+v", last)
+	}
+
+	// once removed the webhook is not retried anymore
+	RemoveWebhook(organizer.ID, hook.ID)
+	clock.Advance(2 * WEBHOOK_FIRST_BACKOFF)
+	time.Sleep(50 * time.Millisecond)
+	waitDeliveries(t, organizer.ID, 2)
+	if clock.Pending() != 0 {
+		t.Fatalf("%d retries still scheduled", clock.Pending())
+	}
+}